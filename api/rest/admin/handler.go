@@ -0,0 +1,166 @@
+package admin
+
+import (
+	"net/http"
+	"runtime"
+	"sort"
+	"time"
+
+	"starter-go/internal/pkg/config"
+	"starter-go/internal/pkg/errors"
+	"starter-go/internal/pkg/health"
+
+	"github.com/gin-gonic/gin"
+)
+
+// started is when the process started, for the uptime
+var started = time.Now()
+
+// configHashHeader carries the config hash along with the config
+const configHashHeader = "X-Config-Hash"
+
+type Handler struct {
+	// servers are the engines whose routes are listed, keyed by server name
+	servers map[string]*gin.Engine
+	checks  *health.Registry
+}
+
+// NewHandler returns the admin handler, the routes of servers are listed by /admin/routes,
+// the results of checks are reported by /admin/health
+func NewHandler(servers map[string]*gin.Engine, checks *health.Registry) *Handler {
+	return &Handler{servers: servers, checks: checks}
+}
+
+// GetHealth runs the readiness checks and returns their errors and timings, the public probe only has their status.
+// It's 503 when a check is down or the service is shutting down, like /healthz/ready.
+func (h *Handler) GetHealth(c *gin.Context) {
+	report := h.checks.Ready(c.Request.Context())
+
+	status := http.StatusOK
+	if report.Status != health.Up {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, report)
+}
+
+// GetConfig returns the effective configuration with secrets redacted, and the source of every key.
+// The format is selected with ?format=yaml|json, defaults to json.
+func (h *Handler) GetConfig(c *gin.Context) {
+	cfg := config.Current()
+	effective := cfg.Effective()
+	c.Header(configHashHeader, cfg.Hash())
+
+	switch c.DefaultQuery("format", "json") {
+	case "json":
+		c.JSON(http.StatusOK, effective)
+	case "yaml":
+		c.YAML(http.StatusOK, effective)
+	default:
+		c.Error(errors.ErrInvalidFieldFormat("format", nil))
+		c.Abort()
+	}
+}
+
+// GetConfigHash returns the hash of the effective configuration, equal on the instances running the same config
+func (h *Handler) GetConfigHash(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"hash": config.Current().Hash(),
+	})
+}
+
+type route struct {
+	Server  string `json:"server"`
+	Method  string `json:"method"`
+	Path    string `json:"path"`
+	Handler string `json:"handler"`
+}
+
+// GetRoutes lists the registered routes of every server
+func (h *Handler) GetRoutes(c *gin.Context) {
+	routes := []route{}
+	for name, e := range h.servers {
+		for _, r := range e.Routes() {
+			routes = append(routes, route{Server: name, Method: r.Method, Path: r.Path, Handler: r.Handler})
+		}
+	}
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].Server != routes[j].Server {
+			return routes[i].Server < routes[j].Server
+		}
+		if routes[i].Path != routes[j].Path {
+			return routes[i].Path < routes[j].Path
+		}
+		return routes[i].Method < routes[j].Method
+	})
+
+	c.JSON(http.StatusOK, routes)
+}
+
+type runtimeStats struct {
+	StartedAt  time.Time   `json:"started_at"`
+	Uptime     string      `json:"uptime"`
+	UptimeSec  int64       `json:"uptime_seconds"`
+	GoVersion  string      `json:"go_version"`
+	NumCPU     int         `json:"num_cpu"`
+	GOMAXPROCS int         `json:"gomaxprocs"`
+	Goroutines int         `json:"goroutines"`
+	Memory     memoryStats `json:"memory"`
+	GC         gcStats     `json:"gc"`
+}
+
+// memoryStats are in bytes
+type memoryStats struct {
+	Alloc       uint64 `json:"alloc"`
+	TotalAlloc  uint64 `json:"total_alloc"`
+	Sys         uint64 `json:"sys"`
+	HeapAlloc   uint64 `json:"heap_alloc"`
+	HeapInuse   uint64 `json:"heap_inuse"`
+	HeapObjects uint64 `json:"heap_objects"`
+	StackInuse  uint64 `json:"stack_inuse"`
+}
+
+type gcStats struct {
+	NumGC        uint32     `json:"num_gc"`
+	LastGC       *time.Time `json:"last_gc"`
+	PauseTotalMs float64    `json:"pause_total_ms"`
+	LastPauseMs  float64    `json:"last_pause_ms"`
+	CPUFraction  float64    `json:"cpu_fraction"`
+}
+
+// GetRuntime returns the goroutines, memory and GC stats and the uptime of the process
+func (h *Handler) GetRuntime(c *gin.Context) {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+
+	uptime := time.Since(started)
+	stats := runtimeStats{
+		StartedAt:  started,
+		Uptime:     uptime.Round(time.Second).String(),
+		UptimeSec:  int64(uptime.Seconds()),
+		GoVersion:  runtime.Version(),
+		NumCPU:     runtime.NumCPU(),
+		GOMAXPROCS: runtime.GOMAXPROCS(0),
+		Goroutines: runtime.NumGoroutine(),
+		Memory: memoryStats{
+			Alloc:       m.Alloc,
+			TotalAlloc:  m.TotalAlloc,
+			Sys:         m.Sys,
+			HeapAlloc:   m.HeapAlloc,
+			HeapInuse:   m.HeapInuse,
+			HeapObjects: m.HeapObjects,
+			StackInuse:  m.StackInuse,
+		},
+		GC: gcStats{
+			NumGC:        m.NumGC,
+			PauseTotalMs: float64(m.PauseTotalNs) / float64(time.Millisecond),
+			CPUFraction:  m.GCCPUFraction,
+		},
+	}
+	if m.NumGC > 0 {
+		last := time.Unix(0, int64(m.LastGC))
+		stats.GC.LastGC = &last
+		stats.GC.LastPauseMs = float64(m.PauseNs[(m.NumGC+255)%256]) / float64(time.Millisecond)
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
@@ -0,0 +1,44 @@
+package admin
+
+import (
+	"expvar"
+	"net/http/pprof"
+
+	"github.com/gin-gonic/gin"
+
+	mw "starter-go/internal/pkg/driver/httpserver/middleware"
+)
+
+// RegisterRoutes registers the ops endpoints on the engine of the admin server, every route requires the bearer token
+func RegisterRoutes(r *gin.Engine, h *Handler, token func() string) {
+	r.Use(mw.BearerToken(token))
+
+	admin := r.Group("/admin")
+	adminRoutes(admin, h)
+
+	debug := r.Group("/debug")
+	debugRoutes(debug)
+}
+
+func adminRoutes(r *gin.RouterGroup, h *Handler) {
+	r.GET("/config", h.GetConfig)
+	r.GET("/config/hash", h.GetConfigHash)
+	r.GET("/health", h.GetHealth)
+	r.GET("/routes", h.GetRoutes)
+	r.GET("/runtime", h.GetRuntime)
+}
+
+// debugRoutes serves pprof under /debug/pprof/ and expvar under /debug/vars, the paths the go tools expect
+func debugRoutes(r *gin.RouterGroup) {
+	r.GET("/vars", gin.WrapH(expvar.Handler()))
+
+	r.GET("/pprof/", gin.WrapF(pprof.Index))
+	r.GET("/pprof/cmdline", gin.WrapF(pprof.Cmdline))
+	r.GET("/pprof/profile", gin.WrapF(pprof.Profile))
+	r.GET("/pprof/symbol", gin.WrapF(pprof.Symbol))
+	r.POST("/pprof/symbol", gin.WrapF(pprof.Symbol))
+	r.GET("/pprof/trace", gin.WrapF(pprof.Trace))
+	for _, profile := range []string{"allocs", "block", "goroutine", "heap", "mutex", "threadcreate"} {
+		r.GET("/pprof/"+profile, gin.WrapH(pprof.Handler(profile)))
+	}
+}
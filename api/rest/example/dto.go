@@ -1,6 +1,10 @@
 package example
 
-import "starter-go/internal/domain/example"
+import (
+	"time"
+
+	"starter-go/internal/domain/example"
+)
 
 type CreateExampleRequest struct {
 	Description string `json:"description" binding:"required,min=1,max=100"`
@@ -9,11 +13,14 @@ type CreateExampleRequest struct {
 type ExampleResponse struct {
 	ID          int    `json:"id"`
 	Description string `json:"description"`
+	// RFC 3339, in the location of server.time_zone
+	CreatedAt string `json:"created_at"`
 }
 
 func FromDomain(e *example.Example) ExampleResponse {
 	return ExampleResponse{
 		ID:          e.ID,
 		Description: e.Description,
+		CreatedAt:   e.CreatedAt.Format(time.RFC3339),
 	}
 }
@@ -4,14 +4,42 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+
+	"starter-go/internal/pkg/buildinfo"
+	"starter-go/internal/pkg/health"
 )
 
-func RegisterRoutes(r *gin.Engine) {
-	r.GET("/healthcheck", healthCheck)
+// RegisterRoutes registers the probes and /version, before the rate limit and timeout middlewares
+// so the probes of the orchestrator are neither limited nor cut short
+func RegisterRoutes(r *gin.Engine, checks *health.Registry) {
+	r.GET("/healthz/live", live)
+	r.GET("/healthz/ready", ready(checks))
+	// kept for the existing probes, same as /healthz/ready
+	r.GET("/healthcheck", ready(checks))
+	r.GET("/version", version)
 }
 
-func healthCheck(c *gin.Context) {
+// live reports the process is up, it doesn't run the checks so a broken dependency doesn't get the process restarted
+func live(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
-		"status": "up",
+		"status": health.Up,
 	})
 }
+
+// ready runs the checks, it's 503 when a check is down or the service is shutting down.
+// Only the status of the checks is returned, their errors are on the admin server (GET /admin/health).
+func ready(checks *health.Registry) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		report := checks.Ready(c.Request.Context())
+
+		status := http.StatusOK
+		if report.Status != health.Up {
+			status = http.StatusServiceUnavailable
+		}
+		c.JSON(status, report.Summary())
+	}
+}
+
+func version(c *gin.Context) {
+	c.JSON(http.StatusOK, buildinfo.Get())
+}
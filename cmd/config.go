@@ -0,0 +1,161 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"starter-go/internal/pkg/config"
+)
+
+func newConfigCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Inspect and validate the config, manage secrets",
+	}
+	cmd.AddCommand(
+		newConfigValidateCommand(),
+		newConfigSchemaCommand(),
+		newConfigPrintCommand(),
+		newConfigGenerateKeyCommand(),
+		newConfigEncryptCommand(),
+	)
+	return cmd
+}
+
+func newConfigValidateCommand() *cobra.Command {
+	var withEnv bool
+	cmd := &cobra.Command{
+		Use:   "validate <file>",
+		Short: "Validate a config file, errors are reported with their line",
+		Long: "Validate a config file and the overlay of its environment, errors are reported with their line.\n" +
+			"The remote config isn't fetched and secret references aren't resolved, so the files can be validated away from the servers.\n" +
+			"The APP_ env vars of the shell are ignored unless --env is set, so the result only depends on the files.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts := []config.LoaderOption{config.WithOffline()}
+			if !withEnv {
+				opts = append(opts, config.WithLookupEnv(func(string) (string, bool) { return "", false }))
+			}
+			_, err := config.NewLoader(args[0], opts...).Load()
+
+			var verr *config.ValidationError
+			if errors.As(err, &verr) {
+				for _, fe := range verr.Errors {
+					fmt.Fprintln(os.Stderr, fe.String())
+				}
+				return fmt.Errorf("%s is invalid", args[0])
+			}
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("%s: OK\n", args[0])
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&withEnv, "env", false, "apply the APP_ env vars of the shell, e.g. APP_SERVER_ENV=production to validate with the production overlay")
+	return cmd
+}
+
+func newConfigSchemaCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "schema",
+		Short: "Print the JSON Schema of the config files",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			schema, err := config.Schema()
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(schema))
+			return nil
+		},
+	}
+}
+
+func newConfigPrintCommand() *cobra.Command {
+	var output string
+	cmd := &cobra.Command{
+		Use:   "print",
+		Short: "Print the effective config, with secrets redacted and the source of every key",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return printConfig(cmd, output)
+		},
+	}
+	cmd.Flags().StringVarP(&output, "output", "o", "yaml", "output format: yaml or json")
+	addConfigFlags(cmd)
+	return cmd
+}
+
+// printConfig loads the config of cmd and prints it in output format, yaml or json
+func printConfig(cmd *cobra.Command, output string) error {
+	if err := initConfig(cmd); err != nil {
+		return err
+	}
+
+	out, err := config.EffectiveConfig().Marshal(output)
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(out))
+	return nil
+}
+
+func newConfigGenerateKeyCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "generate-key",
+		Short: "Print a new random key for APP_CONFIG_KEY",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return generateSecretKey()
+		},
+	}
+}
+
+// generateSecretKey prints a new random key for APP_CONFIG_KEY
+func generateSecretKey() error {
+	key, err := config.GenerateSecretKey()
+	if err != nil {
+		return err
+	}
+	fmt.Println(key)
+	return nil
+}
+
+func newConfigEncryptCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "encrypt",
+		Short: "Encrypt the value read from stdin, and print it as an enc: config value",
+		Long:  "Encrypt the value read from stdin with the key from APP_CONFIG_KEY or APP_CONFIG_KEY_FILE, and print it as an enc: config value.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return encryptSecret()
+		},
+	}
+}
+
+// encryptSecret encrypts the value read from stdin and prints it as an enc: config value
+func encryptSecret() error {
+	key, err := config.LoadSecretKey()
+	if err != nil {
+		return err
+	}
+
+	plaintext, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return err
+	}
+
+	value, err := config.EncryptSecret(strings.TrimRight(string(plaintext), "\r\n"), key)
+	if err != nil {
+		return err
+	}
+	fmt.Println(value)
+	return nil
+}
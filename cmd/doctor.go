@@ -0,0 +1,204 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"starter-go/internal/pkg/clock"
+	"starter-go/internal/pkg/config"
+	"starter-go/internal/pkg/listener"
+	"starter-go/internal/pkg/storage"
+)
+
+// check is a single line of the doctor report
+type check struct {
+	name   string
+	detail string
+	err    error
+}
+
+func newDoctorCommand() *cobra.Command {
+	var timeout time.Duration
+	cmd := &cobra.Command{
+		Use:   "doctor",
+		Short: "Check the config, database, log files and ports, and print a pass/fail report",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			checks := []check{{name: "config", detail: configPath, err: initConfig(cmd)}}
+			// every other check depends on the config
+			if checks[0].err == nil {
+				checks = append(checks,
+					checkDatabase(timeout),
+					checkLogFiles(),
+					checkListener(),
+					checkTLS(),
+				)
+				if config.Admin().GetEnabled() {
+					addr := net.JoinHostPort(config.Admin().GetHost(), strconv.FormatUint(uint64(config.Admin().GetPort()), 10))
+					checks = append(checks, checkPort("admin port", addr))
+				}
+			}
+
+			failed := 0
+			for _, c := range checks {
+				status, detail := "PASS", c.detail
+				if c.err != nil {
+					failed++
+					status, detail = "FAIL", c.err.Error()
+				}
+				// multi-line errors (e.g. config validation) are indented under their check
+				fmt.Printf("%s  %-10s %s\n", status, c.name, strings.ReplaceAll(detail, "\n", "\n"+strings.Repeat(" ", 17)))
+			}
+
+			if failed > 0 {
+				return fmt.Errorf("%d check(s) failed", failed)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().DurationVar(&timeout, "timeout", 5*time.Second, "timeout of the database check")
+	addConfigFlags(cmd)
+	return cmd
+}
+
+func checkDatabase(timeout time.Duration) check {
+	backend := config.Storage().GetBackend()
+	if backend == storage.Memory {
+		return check{name: "database", detail: "not used by the memory storage backend"}
+	}
+
+	conf := config.Database()
+	c := check{name: "database", detail: fmt.Sprintf("%s %s@%s:%d/%s", backend, conf.GetUser(), conf.GetHost(), conf.GetPort(), conf.GetName())}
+
+	done := make(chan error, 1)
+	go func() {
+		db, err := storage.Open(config.Storage(), conf, clock.New(config.Server().GetLocation()))
+		if err == nil {
+			if sqlDB, err := db.DB(); err == nil {
+				_ = sqlDB.Close()
+			}
+		}
+		done <- err
+	}()
+
+	select {
+	case c.err = <-done:
+	case <-time.After(timeout):
+		c.err = fmt.Errorf("no response from %s after %s", c.detail, timeout)
+	}
+	return c
+}
+
+func checkLogFiles() check {
+	conf := config.LoggerConfig()
+	if !conf.EnableLogFile {
+		return check{name: "log files", detail: "disabled"}
+	}
+
+	var paths []string
+	var errs []error
+	for _, f := range conf.LogFileConfigs {
+		paths = append(paths, f.FullpathFilename)
+		if err := writableDir(filepath.Dir(f.FullpathFilename)); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", f.FullpathFilename, err))
+		}
+	}
+	return check{name: "log files", detail: strings.Join(paths, ", "), err: errors.Join(errs...)}
+}
+
+// writableDir checks that a file can be created in dir, or in its closest existing parent since missing directories are created by the logger
+func writableDir(dir string) error {
+	for {
+		if _, err := os.Stat(dir); err == nil {
+			break
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return fmt.Errorf("no existing parent directory")
+		}
+		dir = parent
+	}
+
+	f, err := os.CreateTemp(dir, ".doctor-*")
+	if err != nil {
+		return fmt.Errorf("directory %s is not writable", dir)
+	}
+	_ = f.Close()
+	return os.Remove(f.Name())
+}
+
+// checkTLS loads the certificate and the client CA, and reports when the certificate expires
+func checkTLS() check {
+	conf := config.Server().GetTLS()
+	if !conf.GetEnabled() {
+		return check{name: "tls", detail: "disabled"}
+	}
+
+	c := check{name: "tls", detail: conf.GetCertFile()}
+	pair, err := tls.LoadX509KeyPair(conf.GetCertFile(), conf.GetKeyFile())
+	if err != nil {
+		c.err = err
+		return c
+	}
+	cert, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		c.err = err
+		return c
+	}
+	if time.Now().After(cert.NotAfter) {
+		c.err = fmt.Errorf("%s expired on %s", conf.GetCertFile(), cert.NotAfter.Format(time.RFC3339))
+		return c
+	}
+	c.detail = fmt.Sprintf("%s (%s) expires on %s", conf.GetCertFile(), cert.Subject.CommonName, cert.NotAfter.Format(time.RFC3339))
+
+	if ca := conf.GetClientCA(); ca != "" {
+		pem, err := os.ReadFile(ca)
+		if err == nil && !x509.NewCertPool().AppendCertsFromPEM(pem) {
+			err = fmt.Errorf("no certificate found in %s", ca)
+		}
+		c.err = err
+	}
+	return c
+}
+
+// checkListener checks the public server can listen, on the port, the unix socket, or the socket passed by systemd
+func checkListener() check {
+	conf := config.Server().GetListener()
+	switch conf.GetNetwork() {
+	case listener.Systemd:
+		return check{name: "listener", detail: "socket passed by systemd"}
+	case listener.Unix:
+		c := check{name: "listener", detail: conf.GetSocketPath() + " is available"}
+		if conn, err := net.DialTimeout(listener.Unix, conf.GetSocketPath(), time.Second); err == nil {
+			_ = conn.Close()
+			c.err = fmt.Errorf("%s is in use", conf.GetSocketPath())
+		} else if err := writableDir(filepath.Dir(conf.GetSocketPath())); err != nil {
+			c.err = err
+		}
+		return c
+	default:
+		return checkPort("port", fmt.Sprintf(":%d", config.Server().GetPort()))
+	}
+}
+
+func checkPort(name, addr string) check {
+	c := check{name: name, detail: addr + " is available"}
+
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		c.err = fmt.Errorf("%s is not available: %w", addr, err)
+		return c
+	}
+	_ = l.Close()
+	return c
+}
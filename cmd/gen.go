@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"starter-go/internal/pkg/gen"
+)
+
+func newGenCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "gen",
+		Short: "Generate code",
+	}
+	cmd.AddCommand(newGenDomainCommand())
+	return cmd
+}
+
+func newGenDomainCommand() *cobra.Command {
+	var (
+		fields string
+		dir    string
+		force  bool
+	)
+	cmd := &cobra.Command{
+		Use:   "domain <name>",
+		Short: "Generate a domain like the example one",
+		Long: "Generate the entity, repositories, service, REST handler, module and tests of a domain, " +
+			"following the layering of the example domain.\n\n" +
+			"The name and the fields are snake_case, the field types are: " + fmt.Sprint(gen.FieldTypes) + ".\n" +
+			"Every entity also has an ID and a CreatedAt.",
+		Example: "  gen domain order_item --fields name:string,amount:int,paid:bool",
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			parsed, err := gen.ParseFields(fields)
+			if err != nil {
+				return err
+			}
+			module, err := gen.ModulePath(dir)
+			if err != nil {
+				return fmt.Errorf("--dir must be the root of the Go module: %w", err)
+			}
+
+			d := gen.Domain{Name: args[0], Fields: parsed, Module: module}
+			paths, err := d.Write(dir, force)
+			if err != nil {
+				return err
+			}
+
+			for _, path := range paths {
+				fmt.Printf("created %s\n", path)
+			}
+			fmt.Printf("\nRegister the module in cmd/modules.go:\n\n\t%s.New(),\n\nimported from %q, and create the %s table for the database backends.\n",
+				d.Package(), module+"/internal/module/"+d.Package(), d.Table())
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&fields, "fields", "", "fields of the entity, name:type separated by commas")
+	cmd.Flags().StringVar(&dir, "dir", ".", "root of the Go module to generate the domain in")
+	cmd.Flags().BoolVar(&force, "force", false, "overwrite existing files")
+	_ = cmd.MarkFlagRequired("fields")
+	return cmd
+}
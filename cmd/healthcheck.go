@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"starter-go/internal/pkg/config"
+	"starter-go/internal/pkg/listener"
+)
+
+func newHealthcheckCommand() *cobra.Command {
+	var (
+		url     string
+		timeout time.Duration
+	)
+	cmd := &cobra.Command{
+		Use:   "healthcheck",
+		Short: "Probe the /healthz/ready endpoint of the local server, for Docker HEALTHCHECK",
+		Long:  "Probe the /healthz/ready endpoint of the local server, it exits with 1 when the server isn't ready.\nThe port, unix socket and TLS are read from the config files, env vars and flags, unless --url is set.\nThe remote config isn't fetched and the config isn't validated, so the probe stays cheap.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client := &http.Client{Timeout: timeout}
+			if url == "" {
+				server, err := config.NewLoader(configPath, config.WithFlags(cmd.Flags())).LoadServer()
+				if err != nil {
+					return fmt.Errorf("error parsing config. %w", err)
+				}
+
+				scheme, host := "http", fmt.Sprintf("127.0.0.1:%d", server.GetPort())
+				transport := &http.Transport{}
+				if conf := server.GetTLS(); conf.GetEnabled() {
+					scheme = "https"
+					var err error
+					if transport, err = localTLSTransport(conf); err != nil {
+						return err
+					}
+				}
+				if conf := server.GetListener(); conf.GetNetwork() == listener.Unix {
+					host = "localhost"
+					transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+						var d net.Dialer
+						return d.DialContext(ctx, listener.Unix, conf.GetSocketPath())
+					}
+				}
+				client.Transport = transport
+				url = fmt.Sprintf("%s://%s/healthz/ready", scheme, host)
+			}
+
+			resp, err := client.Get(url)
+			if err != nil {
+				return fmt.Errorf("unhealthy: %w", err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != http.StatusOK {
+				return fmt.Errorf("unhealthy: %s returned %s", url, resp.Status)
+			}
+			fmt.Println("healthy")
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&url, "url", "", "URL to probe, default to the /healthz/ready endpoint on server.port")
+	cmd.Flags().DurationVar(&timeout, "timeout", 3*time.Second, "timeout of the probe")
+	addConfigFlags(cmd)
+	return cmd
+}
+
+// localTLSTransport connects to the local server, its certificate is issued for the public name so it isn't verified.
+// When client certificates are required, the certificate of the server is presented, client_ca must trust it.
+func localTLSTransport(conf config.TLSConfig) (*http.Transport, error) {
+	tlsConf := &tls.Config{InsecureSkipVerify: true}
+	if conf.GetClientAuth() == tls.RequireAndVerifyClientCert {
+		cert, err := tls.LoadX509KeyPair(conf.GetCertFile(), conf.GetKeyFile())
+		if err != nil {
+			return nil, fmt.Errorf("failed to load the client certificate: %w", err)
+		}
+		tlsConf.Certificates = []tls.Certificate{cert}
+	}
+	return &http.Transport{TLSClientConfig: tlsConf}, nil
+}
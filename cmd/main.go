@@ -1,106 +1,94 @@
 package main
 
 import (
-	"context"
-	"flag"
 	"fmt"
 	"os"
-	"os/signal"
-	"syscall"
-	"time"
 
-	server "starter-go/api/rest"
-	"starter-go/api/rest/example"
-	domainExample "starter-go/internal/domain/example"
-	"starter-go/internal/pkg/app"
-	"starter-go/internal/pkg/config"
-	"starter-go/internal/pkg/driver/httpserver"
-	"starter-go/internal/pkg/driver/mysql"
-	"starter-go/internal/pkg/logger"
-	exampleRepo "starter-go/internal/repository/example"
-	exampleService "starter-go/internal/service/example"
-)
+	"github.com/spf13/cobra"
 
-// build vars
-const (
-	GitCommit  = "unknown"
-	AppVersion = "unknown"
-	AppName    = "starter-go"
+	"starter-go/internal/pkg/buildinfo"
+	"starter-go/internal/pkg/config"
 )
 
 func main() {
-	cfgPath := flag.String("configpath", "./config/config.yaml", "path to config file")
-	flag.Parse()
-
-	err := config.Init(*cfgPath)
-	if err != nil {
-		panic(fmt.Errorf("error parsing config. %w", err))
-	}
-
-	buildInfo := map[string]string{
-		"commit": GitCommit,
+	if err := newRootCommand().Execute(); err != nil {
+		os.Exit(1)
 	}
+}
 
-	newLogger, err := logger.NewFromConfig(config.LoggerConfig())
-	if err != nil {
-		panic(fmt.Errorf("failed to create logger"))
+func newRootCommand() *cobra.Command {
+	root := &cobra.Command{
+		Use:   buildinfo.Name,
+		Short: "Starter Go service",
+		Long:  "Starter Go service, without a command it starts the HTTP server like serve.\n\n" + config.FlagsUsage,
+		Args:  cobra.NoArgs,
+		// errors are printed by cobra, the usage would hide them
+		SilenceUsage: true,
+		RunE:         runRoot,
 	}
-	defer newLogger.Stop()
-
-	logger.SetDefaultLogger(newLogger.
-		With("buildinfo", buildInfo).
-		With("version", AppVersion).
-		With("service", AppName))
-
-	// init HTTP Server
-	srv := httpserver.NewServer()
-	server.RegisterRoutes(srv.Engine())
-
-	// initialize MySQL Database
-	db := mysql.NewDatabase()
-	exampleRepo := exampleRepo.NewExampleRepository(db)
-	logger.Info("Starting application with mysql repository")
-
-	// init inmemory repository (for example only, can replace with actual db)
-	// exampleRepo := inMemoryInit()
-	// logger.Info("Starting application with inmemory repository")
+	addConfigFlags(root)
+	addLegacyFlags(root)
+
+	root.AddCommand(
+		newServeCommand(),
+		newVersionCommand(),
+		newHealthcheckCommand(),
+		newDoctorCommand(),
+		newConfigCommand(),
+		newGenCommand(),
+	)
+	return root
+}
 
-	// initialize service and handler
-	exSvc := exampleService.NewService(exampleRepo)
-	exHdlr := example.NewHandler(exSvc)
+// legacyFlags are the flags of the CLI from before the commands, kept as deprecated aliases of the commands
+var legacyFlags struct {
+	printConfig       bool
+	output            string
+	encryptSecret     bool
+	generateSecretKey bool
+}
 
-	example.RegisterRoutes(srv.Engine(), exHdlr)
+// addLegacyFlags adds the deprecated flags to the root command, they're hidden from --help and warn when used
+func addLegacyFlags(cmd *cobra.Command) {
+	fs := cmd.Flags()
+	fs.BoolVar(&legacyFlags.printConfig, "print-config", false, "print the effective config, with secrets redacted and the source of every key, then exit")
+	fs.StringVar(&legacyFlags.output, "output", "yaml", "format of --print-config: yaml or json")
+	fs.BoolVar(&legacyFlags.encryptSecret, "encrypt-secret", false, "encrypt the value read from stdin with the key from APP_CONFIG_KEY or APP_CONFIG_KEY_FILE, and print it as an enc: config value")
+	fs.BoolVar(&legacyFlags.generateSecretKey, "generate-secret-key", false, "print a new random key for APP_CONFIG_KEY")
+	_ = fs.MarkDeprecated("print-config", "use config print instead")
+	_ = fs.MarkDeprecated("output", "use config print --output instead")
+	_ = fs.MarkDeprecated("encrypt-secret", "use config encrypt instead")
+	_ = fs.MarkDeprecated("generate-secret-key", "use config generate-key instead")
+}
 
-	apps := []app.App{
-		srv,
+// runRoot runs serve, unless a deprecated flag asks for another command
+func runRoot(cmd *cobra.Command, args []string) error {
+	switch {
+	case legacyFlags.encryptSecret:
+		return encryptSecret()
+	case legacyFlags.generateSecretKey:
+		return generateSecretKey()
+	case legacyFlags.printConfig:
+		return printConfig(cmd, legacyFlags.output)
 	}
+	return runServe(cmd, args)
+}
 
-	stopFn := app.AppController(apps...)
-
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGTERM, os.Interrupt)
-
-	sig := <-quit
-	logger.Info(fmt.Sprintf("exiting. received signal: %s", sig.String()))
+// configPath is the --configpath flag of the command being run
+var configPath string
 
-	stopFn(time.Duration(30) * time.Second)
+// addConfigFlags adds --configpath and a flag for every config key to the commands reading the config
+func addConfigFlags(cmd *cobra.Command) {
+	fs := cmd.Flags()
+	fs.SortFlags = false
+	fs.StringVar(&configPath, "configpath", "./config/config.yaml", "path to config file")
+	config.BindFlags(fs)
 }
 
-// just for example, should remove if not used
-func inMemoryInit() *exampleRepo.MemoryRepository {
-	exampleRepository := exampleRepo.NewMemoryRepository()
-
-	// prepopulate with some examples for testing
-	ctx := context.Background()
-	err := exampleRepository.Preload(
-		ctx,
-		&domainExample.Example{Description: "Example 1"},
-		&domainExample.Example{Description: "Example 2"},
-	)
-	if err != nil {
-		logger.Error("Failed to preload examples", "error", err.Error())
-	} else {
-		logger.Info("Pre-populated repository with examples")
+// initConfig loads the config of cmd, flags set on the command line override the config keys
+func initConfig(cmd *cobra.Command) error {
+	if err := config.Init(configPath, config.WithFlags(cmd.Flags())); err != nil {
+		return fmt.Errorf("error parsing config. %w", err)
 	}
-	return exampleRepository
+	return nil
 }
@@ -0,0 +1,13 @@
+package main
+
+import (
+	"starter-go/internal/module/example"
+	"starter-go/internal/pkg/module"
+)
+
+// modules lists the domain modules of the service, a new domain is registered here
+func modules() []module.Module {
+	return []module.Module{
+		example.New(),
+	}
+}
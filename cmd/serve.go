@@ -0,0 +1,317 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"syscall"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/cobra"
+
+	server "starter-go/api/rest"
+	"starter-go/api/rest/admin"
+	"starter-go/internal/pkg/app"
+	"starter-go/internal/pkg/buildinfo"
+	"starter-go/internal/pkg/clock"
+	"starter-go/internal/pkg/config"
+	"starter-go/internal/pkg/driver/httpserver"
+	mw "starter-go/internal/pkg/driver/httpserver/middleware"
+	"starter-go/internal/pkg/flags"
+	"starter-go/internal/pkg/health"
+	"starter-go/internal/pkg/listener"
+	"starter-go/internal/pkg/logger"
+	"starter-go/internal/pkg/module"
+	"starter-go/internal/pkg/ratelimit"
+	"starter-go/internal/pkg/scheduler"
+	"starter-go/internal/pkg/storage"
+)
+
+func newServeCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Start the HTTP server, the default command",
+		Long:  "Start the HTTP server, it runs until SIGTERM or SIGINT. SIGHUP reloads the config, SIGUSR2 starts a new process taking over the listeners.\n\n" + config.FlagsUsage,
+		Args:  cobra.NoArgs,
+		RunE:  runServe,
+	}
+	addConfigFlags(cmd)
+	return cmd
+}
+
+func runServe(cmd *cobra.Command, _ []string) error {
+	if err := initConfig(cmd); err != nil {
+		return err
+	}
+
+	newLogger, err := newAppLogger(config.LoggerConfig())
+	if err != nil {
+		return fmt.Errorf("failed to create logger: %w", err)
+	}
+	logger.ReplaceDefaultLogger(newLogger)
+	defer func() { logger.Default().Stop() }()
+
+	// rebuild the logger when its level or sinks change on config reload,
+	// the previous one is closed once the requests logging through it are done
+	config.OnLoggerChange(func(conf logger.LogConfig) {
+		reloaded, err := newAppLogger(conf)
+		if err != nil {
+			logger.Error("failed to apply logger config, keeping current logger", "error", err.Error())
+			return
+		}
+		logger.ReplaceDefaultLogger(reloaded)
+	})
+	config.Watch()
+
+	// registered early so a SIGHUP during startup doesn't terminate the process
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, append([]os.Signal{syscall.SIGTERM, os.Interrupt, syscall.SIGHUP}, upgradeSignals...)...)
+
+	// init HTTP Server
+	srv := httpserver.NewServer(config.Server(), config.CORS())
+	config.OnCORSChange(func(conf config.CORSConfig) {
+		if err := srv.UpdateCORS(conf); err != nil {
+			logger.Error("failed to apply CORS config, keeping current origins", "error", err.Error())
+		}
+	})
+
+	clk := clock.New(config.Server().GetLocation())
+
+	// checks are registered once the modules are initialized, the routes only read the registry.
+	// The probes are registered before the middlewares below, so they're neither rate limited nor timed out.
+	checks := health.NewRegistry(
+		time.Duration(config.Health().GetCheckTimeout())*time.Millisecond,
+		time.Duration(config.Health().GetCacheTTL())*time.Millisecond,
+	)
+	server.RegisterRoutes(srv.Engine(), checks)
+
+	// feature flags are registered before the routes so every route can evaluate them
+	featureFlags := flags.New(config.Flags())
+	config.OnFlagsChange(featureFlags.Update)
+	// the user id of the rollouts by user_id is the verified client certificate, see server.tls.client_auth
+	srv.Engine().Use(mw.Flags(featureFlags, mw.ClientCertSubject))
+
+	// the clients are limited per instance, the buckets are kept in memory.
+	// API keys are checked against ratelimit.api_keys of the current config, so they're reloaded with it.
+	limiter := mw.NewRateLimit(
+		ratelimit.NewMemoryStore(clk, int(config.RateLimit().GetMaxKeys())),
+		config.RateLimit().GetAPIKeyHeader(),
+		config.RateLimit().GetPolicies(),
+		mw.ClientCertSubject,
+		func(c *gin.Context, key string) (string, bool) {
+			return config.RateLimit().GetAPIKeys().Lookup(key)
+		},
+	)
+	config.OnRateLimitChange(func(conf config.RateLimitConfig) {
+		limiter.SetPolicies(conf.GetAPIKeyHeader(), conf.GetPolicies())
+	})
+	srv.Engine().Use(limiter.Handler())
+
+	// registered before the routes, the timeout of a route is looked up by its path on every request
+	timeouts := mw.NewTimeout(requestTimeouts(config.Timeout()))
+	config.OnTimeoutChange(func(conf config.TimeoutConfig) {
+		timeouts.SetTimeouts(requestTimeouts(conf))
+	})
+	srv.Engine().Use(timeouts.Handler())
+
+	// the database is only opened by the backends needing one
+	backend := config.Storage().GetBackend()
+	db := storage.NewDatabase(config.Storage(), config.Database(), clk)
+	logger.Info(fmt.Sprintf("Starting application with %s storage", backend))
+
+	jobs := scheduler.New(clk)
+	registry := module.NewRegistry()
+	if err := registry.Register(modules()...); err != nil {
+		return err
+	}
+	err = registry.Init(module.Resources{
+		DB:        db,
+		Storage:   backend,
+		Clock:     clk,
+		Flags:     featureFlags,
+		Scheduler: jobs,
+	})
+	if err != nil {
+		return err
+	}
+	registry.RegisterRoutes(srv.Engine())
+	registerHealthChecks(checks, registry)
+
+	apps := append([]app.App{srv}, registry.Apps()...)
+	if config.Admin().GetEnabled() {
+		apps = append(apps, newAdminServer(srv.Engine(), checks))
+	}
+	if config.Scheduler().GetEnabled() {
+		apps = append(apps, jobs)
+		// a failed run is reported, it doesn't take the instance out of rotation
+		for name, check := range jobs.HealthChecks() {
+			checks.Register("scheduler/"+name, check, health.NonCritical())
+		}
+	} else {
+		logger.Info("scheduler disabled, the scheduled jobs don't run")
+	}
+	controller, err := app.AppController(apps...)
+	if err != nil {
+		return err
+	}
+
+	if err := controller.Start(cmd.Context()); err != nil {
+		// the apps started before are stopped in order
+		logger.Error("failed to start application", "error", err.Error())
+		_ = shutdown(controller, checks, quit, 0)
+		return err
+	}
+	// after an upgrade, the previous process drains once this one serves
+	listener.Ready()
+
+	upgraded := make(chan error, 1)
+	for {
+		select {
+		case sig := <-quit:
+			switch {
+			case isUpgradeSignal(sig):
+				logger.Info(fmt.Sprintf("[Upgrade] received signal: %s, starting a new process", sig.String()))
+				go func() {
+					upgraded <- listener.Upgrade(time.Duration(config.Server().GetListener().GetUpgradeTimeout()) * time.Millisecond)
+				}()
+			case sig == syscall.SIGHUP:
+				logger.Info("received SIGHUP, reloading config")
+				_ = config.Reload()
+			default:
+				logger.Info(fmt.Sprintf("exiting. received signal: %s", sig.String()))
+				return shutdown(controller, checks, quit, time.Duration(config.Server().GetShutdownDelay())*time.Millisecond)
+			}
+		case err := <-upgraded:
+			if err != nil {
+				logger.Error("[Upgrade] failed, keeping serving", "error", err.Error())
+				continue
+			}
+			// the new process accepts the connections now, the delay lets the ones accepted here send their request before draining
+			logger.Info("[Upgrade] new process ready, exiting")
+			return shutdown(controller, checks, quit, time.Duration(config.Server().GetShutdownDelay())*time.Millisecond)
+		case err := <-controller.Failed():
+			logger.Error("application failed, exiting", "error", err.Error())
+			_ = shutdown(controller, checks, quit, 0)
+			return err
+		}
+	}
+}
+
+// shutdown turns the readiness down, waits delay for the load balancers to stop routing traffic,
+// then stops the apps within server.shutdown_timeout, the HTTP server drains its in-flight requests.
+// The returned error names the apps that failed to stop.
+func shutdown(controller *app.Controller, checks *health.Registry, quit <-chan os.Signal, delay time.Duration) error {
+	start := time.Now()
+	checks.Shutdown()
+	logger.Info("[Shutdown] readiness down", "delay", delay.String())
+
+	if delay > 0 {
+		waitShutdownDelay(quit, delay)
+		logger.Info("[Shutdown] shutdown delay over", "duration", time.Since(start).String())
+	}
+
+	timeout := time.Duration(config.Server().GetShutdownTimeout()) * time.Millisecond
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	stopStart := time.Now()
+	if err := controller.Stop(ctx).Err(); err != nil {
+		logger.Error("[Shutdown] apps stopped with errors", "duration", time.Since(stopStart).String(), "total_duration", time.Since(start).String(), "error", err.Error())
+		return err
+	}
+	logger.Info("[Shutdown] apps stopped", "duration", time.Since(stopStart).String(), "total_duration", time.Since(start).String())
+	return nil
+}
+
+// waitShutdownDelay waits delay, a second SIGTERM or SIGINT cuts it short, SIGHUP and upgrades are ignored once shutting down
+func waitShutdownDelay(quit <-chan os.Signal, delay time.Duration) {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-timer.C:
+			return
+		case sig := <-quit:
+			if sig == syscall.SIGHUP || isUpgradeSignal(sig) {
+				logger.Info(fmt.Sprintf("[Shutdown] ignoring %s, shutting down", sig.String()))
+				continue
+			}
+			logger.Warn(fmt.Sprintf("[Shutdown] received signal: %s, skipping the rest of the shutdown delay", sig.String()))
+			return
+		}
+	}
+}
+
+// requestTimeouts returns the default timeout and the timeouts of the route groups of conf
+func requestTimeouts(conf config.TimeoutConfig) (time.Duration, map[string]time.Duration) {
+	routes := map[string]time.Duration{}
+	for path, timeout := range conf.GetRoutes() {
+		routes[path] = time.Duration(timeout) * time.Millisecond
+	}
+	return time.Duration(conf.GetDefault()) * time.Millisecond, routes
+}
+
+func isUpgradeSignal(sig os.Signal) bool {
+	return slices.Contains(upgradeSignals, sig)
+}
+
+// newAdminServer returns the admin server of the ops endpoints, public is the engine of the public server whose routes are listed
+func newAdminServer(public *gin.Engine, checks *health.Registry) app.App {
+	adminSrv := httpserver.NewAdminServer(config.Admin(), config.Server())
+	handler := admin.NewHandler(map[string]*gin.Engine{
+		"http":  public,
+		"admin": adminSrv.Engine(),
+	}, checks)
+	// read on every request, so a token changed on reload applies right away
+	admin.RegisterRoutes(adminSrv.Engine(), handler, func() string {
+		return config.Admin().GetToken()
+	})
+	return adminSrv
+}
+
+// registerHealthChecks adds the checks of the modules, the log files and the disk of the log files to the readiness probe
+func registerHealthChecks(checks *health.Registry, registry *module.Registry) {
+	for name, check := range registry.HealthChecks() {
+		checks.Register(name, health.Check(check))
+	}
+
+	// the logger is replaced on config reload, the check always reads the current one
+	checks.Register("logger", func(ctx context.Context) error {
+		return logger.SinkErr()
+	}, health.WithCacheTTL(0))
+
+	logConf := config.LoggerConfig()
+	if !logConf.EnableLogFile {
+		return
+	}
+	minFree := uint64(config.Health().GetMinFreeDisk()) << 20
+	seen := map[string]bool{}
+	for _, file := range logConf.LogFileConfigs {
+		dir := filepath.Dir(file.FullpathFilename)
+		if seen[dir] {
+			continue
+		}
+		seen[dir] = true
+		checks.Register("disk:"+dir, health.DiskSpace(dir, minFree))
+	}
+}
+
+// newAppLogger creates the logger from config, tagged with the build info
+func newAppLogger(conf logger.LogConfig) (*logger.Logger, error) {
+	l, err := logger.NewFromConfig(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	info := buildinfo.Summary()
+	tagged := l.
+		With("buildinfo", info).
+		With("version", info.Version).
+		With("service", info.Name)
+	return &tagged, nil
+}
@@ -0,0 +1,8 @@
+//go:build !unix
+
+package main
+
+import "os"
+
+// upgradeSignals are unsupported, the listeners can't be handed over
+var upgradeSignals []os.Signal
@@ -0,0 +1,11 @@
+//go:build unix
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// upgradeSignals start a new process of the binary taking over the listeners, see listener.Upgrade
+var upgradeSignals = []os.Signal{syscall.SIGUSR2}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"starter-go/internal/pkg/buildinfo"
+)
+
+func newVersionCommand() *cobra.Command {
+	var asJSON bool
+	cmd := &cobra.Command{
+		Use:   "version",
+		Short: "Print the build info",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			info := buildinfo.Get()
+			if asJSON {
+				out, err := json.MarshalIndent(info, "", "  ")
+				if err != nil {
+					return err
+				}
+				fmt.Println(string(out))
+				return nil
+			}
+
+			commit := info.Commit
+			if info.Dirty {
+				commit += " (dirty)"
+			}
+			fmt.Printf("%s %s\ncommit: %s\nbuilt: %s\ngo: %s\n", info.Name, info.Version, commit, info.BuildTime, info.GoVersion)
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&asJSON, "json", false, "print the build info as JSON, including the module deps")
+	return cmd
+}
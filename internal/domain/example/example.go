@@ -1,6 +1,9 @@
 package example
 
+import "time"
+
 type Example struct {
 	ID          int
 	Description string
+	CreatedAt   time.Time
 }
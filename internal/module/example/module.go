@@ -0,0 +1,76 @@
+package example
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	rest "starter-go/api/rest/example"
+	domain "starter-go/internal/domain/example"
+	"starter-go/internal/pkg/module"
+	repository "starter-go/internal/repository/example"
+	service "starter-go/internal/service/example"
+)
+
+// compile-time check to ensure Module implements the module interfaces
+var (
+	_ module.Module        = (*Module)(nil)
+	_ module.HealthChecker = (*Module)(nil)
+)
+
+// Module wires the example domain: repository, service and handler
+type Module struct {
+	db      *gorm.DB
+	service domain.ExampleService
+	handler *rest.Handler
+}
+
+func New() *Module {
+	return &Module{}
+}
+
+func (m *Module) Name() string {
+	return "example"
+}
+
+func (m *Module) Dependencies() []string {
+	return nil
+}
+
+func (m *Module) Init(res module.Resources) error {
+	repo, err := repository.NewRepository(res.Storage, res.DB, res.Clock)
+	if err != nil {
+		return err
+	}
+
+	m.db = res.DB
+	m.service = service.NewService(repo, res.Clock)
+	m.handler = rest.NewHandler(m.service)
+
+	return nil
+}
+
+func (m *Module) RegisterRoutes(r *gin.Engine) {
+	rest.RegisterRoutes(r, m.handler)
+}
+
+// Service is the example service, for modules depending on this one
+func (m *Module) Service() domain.ExampleService {
+	return m.service
+}
+
+func (m *Module) HealthChecks() map[string]module.HealthCheck {
+	if m.db == nil {
+		return nil
+	}
+	return map[string]module.HealthCheck{
+		"database": func(ctx context.Context) error {
+			sqlDB, err := m.db.DB()
+			if err != nil {
+				return err
+			}
+			return sqlDB.PingContext(ctx)
+		},
+	}
+}
@@ -1,43 +1,241 @@
 package app
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
 	"sync"
 	"time"
+
+	"starter-go/internal/pkg/logger"
 )
 
+// App is a component of the service with a lifecycle, e.g. the HTTP server.
+// Start returns once the app is started, or the reason it couldn't start, the app then runs in the background.
+// Stop returns once the app is stopped, it must give up when ctx is done.
 type App interface {
-	Start()
-	Stop()
+	Start(ctx context.Context) error
+	Stop(ctx context.Context) error
+}
+
+// Named apps are named in logs and stop reports, other apps are named after their type
+type Named interface {
+	Name() string
+}
+
+// Dependent apps are started after the apps they depend on, and stopped before them
+type Dependent interface {
+	// Dependencies are the names of the apps this one depends on
+	Dependencies() []string
+}
+
+// Failer apps can fail after they started, e.g. a server whose listener breaks.
+// The channel receives the error, it's never closed.
+type Failer interface {
+	Failed() <-chan error
+}
+
+// Name is the name of the app in logs and stop reports
+func Name(a App) string {
+	if n, ok := a.(Named); ok {
+		return n.Name()
+	}
+	return fmt.Sprintf("%T", a)
+}
+
+// Controller starts apps in dependency order and stops them in reverse order
+type Controller struct {
+	apps []App
+
+	mu      sync.Mutex
+	started []App
+	failed  chan error
+	stopped chan struct{}
+}
+
+// AppController returns the controller of apps, ordered by their dependencies, otherwise in the order given.
+// It fails on unknown dependencies and cycles.
+func AppController(apps ...App) (*Controller, error) {
+	sorted, err := sortApps(apps)
+	if err != nil {
+		return nil, err
+	}
+	return &Controller{
+		apps:    sorted,
+		failed:  make(chan error, 1),
+		stopped: make(chan struct{}),
+	}, nil
+}
+
+// Start starts the apps one after another, it stops at the first app failing to start and returns its error.
+// The apps started before keep running, Stop stops them.
+func (c *Controller) Start(ctx context.Context) error {
+	for _, a := range c.apps {
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("app %s: %w", Name(a), err)
+		}
+		if err := a.Start(ctx); err != nil {
+			return fmt.Errorf("app %s: %w", Name(a), err)
+		}
+		logger.Info("[App] started", "app", Name(a))
+
+		c.mu.Lock()
+		c.started = append(c.started, a)
+		c.mu.Unlock()
+
+		if f, ok := a.(Failer); ok {
+			go c.watch(a, f)
+		}
+	}
+	return nil
+}
+
+// Failed receives the error of the first app failing after it started, the service should then be stopped
+func (c *Controller) Failed() <-chan error {
+	return c.failed
+}
+
+func (c *Controller) watch(a App, f Failer) {
+	select {
+	case err := <-f.Failed():
+		select {
+		case c.failed <- fmt.Errorf("app %s: %w", Name(a), err):
+		default:
+		}
+	case <-c.stopped:
+	}
 }
 
-func AppController(apps ...App) (stopFn func(duration time.Duration)) {
-	var wg sync.WaitGroup
+// Stop stops the started apps in reverse order, each app is given what's left of the ctx deadline.
+// An app that doesn't stop in time is reported as timed out and left behind, the next ones are still stopped.
+func (c *Controller) Stop(ctx context.Context) StopReport {
+	c.mu.Lock()
+	started := c.started
+	c.started = nil
+	c.mu.Unlock()
 
-	for _, t := range apps {
-		wg.Add(1)
-		go t.Start()
+	select {
+	case <-c.stopped:
+	default:
+		close(c.stopped)
 	}
 
-	return func(duration time.Duration) {
-		for _, t := range apps {
-			go func(t App) {
-				t.Stop()
-				wg.Done()
-			}(t)
+	report := make(StopReport, 0, len(started))
+	for i := len(started) - 1; i >= 0; i-- {
+		result := stopApp(ctx, started[i])
+		if result.Err != nil {
+			logger.Error("[App] failed to stop", "app", result.Name, "timed_out", result.TimedOut, "duration", result.Duration.String(), "error", result.Err.Error())
+		} else {
+			logger.Info("[App] stopped", "app", result.Name, "duration", result.Duration.String())
 		}
+		report = append(report, result)
+	}
+	return report
+}
+
+func stopApp(ctx context.Context, a App) StopResult {
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		done <- a.Stop(ctx)
+	}()
 
+	result := StopResult{Name: Name(a)}
+	select {
+	case result.Err = <-done:
+	case <-ctx.Done():
+		// apps giving up on ctx return right after it's done, or stop right away when it's done already
 		select {
-		case <-time.After(duration):
-			return
-		case <-func() chan struct{} {
-			c := make(chan struct{})
-			go func() {
-				wg.Wait()
-				close(c)
-			}()
-			return c
-		}():
-			return
+		case result.Err = <-done:
+		case <-time.After(stopGrace):
+			result.Err = ctx.Err()
+		}
+	}
+	result.TimedOut = errors.Is(result.Err, context.DeadlineExceeded)
+	result.Duration = time.Since(start)
+	return result
+}
+
+// stopGrace is how long an app is waited for after the stop deadline, before it's reported as timed out and left behind
+const stopGrace = 100 * time.Millisecond
+
+// StopResult is how an app stopped
+type StopResult struct {
+	Name     string
+	Err      error
+	TimedOut bool
+	Duration time.Duration
+}
+
+// StopReport lists the stopped apps, in stop order
+type StopReport []StopResult
+
+// Err names the apps that failed or timed out, nil when every app stopped
+func (r StopReport) Err() error {
+	var failed []string
+	var errs []error
+	for _, result := range r {
+		switch {
+		case result.TimedOut:
+			failed = append(failed, result.Name+" (timed out)")
+		case result.Err != nil:
+			failed = append(failed, result.Name)
+		default:
+			continue
+		}
+		errs = append(errs, result.Err)
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+	return fmt.Errorf("apps failed to stop: %s: %w", strings.Join(failed, ", "), errors.Join(errs...))
+}
+
+// sortApps puts every app after its dependencies, otherwise in the given order
+func sortApps(apps []App) ([]App, error) {
+	byName := make(map[string]App, len(apps))
+	for _, a := range apps {
+		if _, ok := byName[Name(a)]; ok {
+			return nil, fmt.Errorf("app %s is registered twice", Name(a))
+		}
+		byName[Name(a)] = a
+	}
+
+	sorted := make([]App, 0, len(apps))
+	state := map[string]int{} // 1: visiting, 2: done
+
+	var visit func(a App, path []string) error
+	visit = func(a App, path []string) error {
+		name := Name(a)
+		switch state[name] {
+		case 1:
+			return fmt.Errorf("app dependency cycle: %s", strings.Join(append(path, name), " -> "))
+		case 2:
+			return nil
+		}
+
+		state[name] = 1
+		if d, ok := a.(Dependent); ok {
+			for _, dep := range d.Dependencies() {
+				da, ok := byName[dep]
+				if !ok {
+					return fmt.Errorf("app %s depends on unknown app %s", name, dep)
+				}
+				if err := visit(da, append(path, name)); err != nil {
+					return err
+				}
+			}
+		}
+		state[name] = 2
+		sorted = append(sorted, a)
+		return nil
+	}
+
+	for _, a := range apps {
+		if err := visit(a, nil); err != nil {
+			return nil, err
 		}
 	}
+	return sorted, nil
 }
@@ -0,0 +1,165 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeApp records its start and stop in events
+type fakeApp struct {
+	name      string
+	deps      []string
+	startErr  error
+	stopDelay time.Duration
+	failed    chan error
+
+	mu     *sync.Mutex
+	events *[]string
+}
+
+func (a *fakeApp) Name() string           { return a.name }
+func (a *fakeApp) Dependencies() []string { return a.deps }
+func (a *fakeApp) Failed() <-chan error   { return a.failed }
+
+func (a *fakeApp) Start(ctx context.Context) error {
+	a.record("start " + a.name)
+	return a.startErr
+}
+
+func (a *fakeApp) Stop(ctx context.Context) error {
+	if a.stopDelay > 0 {
+		select {
+		case <-time.After(a.stopDelay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	a.record("stop " + a.name)
+	return nil
+}
+
+func (a *fakeApp) record(event string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	*a.events = append(*a.events, event)
+}
+
+func newFakeApps(names ...string) ([]*fakeApp, *[]string) {
+	var mu sync.Mutex
+	events := &[]string{}
+	apps := make([]*fakeApp, len(names))
+	for i, name := range names {
+		apps[i] = &fakeApp{name: name, failed: make(chan error, 1), mu: &mu, events: events}
+	}
+	return apps, events
+}
+
+func asApps(fakes []*fakeApp) []App {
+	apps := make([]App, len(fakes))
+	for i, f := range fakes {
+		apps[i] = f
+	}
+	return apps
+}
+
+func TestControllerOrder(t *testing.T) {
+	fakes, events := newFakeApps("http", "worker", "db")
+	fakes[0].deps = []string{"worker"}
+	fakes[1].deps = []string{"db"}
+
+	c, err := AppController(asApps(fakes)...)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := c.Start(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := c.Stop(context.Background()).Err(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []string{"start db", "start worker", "start http", "stop http", "stop worker", "stop db"}
+	if !reflect.DeepEqual(*events, want) {
+		t.Errorf("expected %v, got %v", want, *events)
+	}
+}
+
+func TestControllerStartFailure(t *testing.T) {
+	fakes, events := newFakeApps("a", "b", "c")
+	fakes[1].startErr = errors.New("port in use")
+
+	c, _ := AppController(asApps(fakes)...)
+	err := c.Start(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "app b: port in use") {
+		t.Fatalf("expected b to fail, got %v", err)
+	}
+
+	// only the apps started before are stopped
+	report := c.Stop(context.Background())
+	if len(report) != 1 || report[0].Name != "a" {
+		t.Errorf("expected only a to be stopped, got %+v", report)
+	}
+	want := []string{"start a", "start b", "stop a"}
+	if !reflect.DeepEqual(*events, want) {
+		t.Errorf("expected %v, got %v", want, *events)
+	}
+}
+
+func TestControllerStopReport(t *testing.T) {
+	fakes, _ := newFakeApps("fast", "slow")
+	fakes[1].stopDelay = time.Minute
+
+	c, _ := AppController(asApps(fakes)...)
+	if err := c.Start(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	report := c.Stop(ctx)
+
+	if len(report) != 2 || report[0].Name != "slow" || !report[0].TimedOut {
+		t.Fatalf("expected slow to time out first, got %+v", report)
+	}
+	err := report.Err()
+	if err == nil || !strings.Contains(err.Error(), "slow (timed out)") || strings.Contains(err.Error(), "fast") {
+		t.Errorf("expected only slow in the report error, got %v", err)
+	}
+}
+
+func TestControllerFailed(t *testing.T) {
+	fakes, _ := newFakeApps("http")
+	c, _ := AppController(asApps(fakes)...)
+	if err := c.Start(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	fakes[0].failed <- errors.New("listener closed")
+	select {
+	case err := <-c.Failed():
+		if !strings.Contains(err.Error(), "app http: listener closed") {
+			t.Errorf("unexpected error: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the failure to be reported")
+	}
+}
+
+func TestAppControllerErrors(t *testing.T) {
+	fakes, _ := newFakeApps("a", "b")
+	fakes[0].deps = []string{"b"}
+	fakes[1].deps = []string{"a"}
+	if _, err := AppController(asApps(fakes)...); err == nil || !strings.Contains(err.Error(), "a -> b -> a") {
+		t.Errorf("expected a cycle, got %v", err)
+	}
+
+	fakes[1].deps = []string{"missing"}
+	if _, err := AppController(asApps(fakes)...); err == nil || !strings.Contains(err.Error(), "unknown app missing") {
+		t.Errorf("expected an unknown dependency, got %v", err)
+	}
+}
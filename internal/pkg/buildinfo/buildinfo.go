@@ -0,0 +1,108 @@
+package buildinfo
+
+import (
+	"runtime"
+	"runtime/debug"
+	"sync"
+)
+
+// Name of the service, reported in logs and by GET /version
+const Name = "starter-go"
+
+// Set at build time, e.g.
+//
+//	go build -ldflags "-X starter-go/internal/pkg/buildinfo.version=v1.2.0 -X starter-go/internal/pkg/buildinfo.commit=$(git rev-parse HEAD) -X starter-go/internal/pkg/buildinfo.buildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)" ./cmd
+//
+// Unset values fall back to what the go toolchain stamped in the binary, see debug.ReadBuildInfo.
+var (
+	version   string
+	commit    string
+	buildTime string
+)
+
+const unknown = "unknown"
+
+// Info describes the running binary
+type Info struct {
+	Build
+	Deps []Dep `json:"deps,omitempty"`
+}
+
+// Build is the build info without the deps, it's attached to logs
+type Build struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	Commit  string `json:"commit"`
+	// Dirty is set when the binary was built from a work tree with uncommitted changes
+	Dirty bool `json:"dirty"`
+	// BuildTime is the time of the build when set with ldflags, or the time of the commit otherwise
+	BuildTime string `json:"build_time"`
+	GoVersion string `json:"go_version"`
+}
+
+// Dep is a module the binary was built with
+type Dep struct {
+	Path    string `json:"path"`
+	Version string `json:"version"`
+}
+
+var (
+	once sync.Once
+	info Info
+)
+
+// Get returns the build info, it's read once
+func Get() Info {
+	once.Do(func() {
+		info = read()
+	})
+	return info
+}
+
+// Summary returns the build info without the deps, to be attached to logs
+func Summary() Build {
+	return Get().Build
+}
+
+func read() Info {
+	i := Info{Build: Build{
+		Name:      Name,
+		Version:   version,
+		Commit:    commit,
+		BuildTime: buildTime,
+		GoVersion: runtime.Version(),
+	}}
+
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		if i.Version == "" && bi.Main.Version != "(devel)" {
+			i.Version = bi.Main.Version
+		}
+		for _, s := range bi.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				if i.Commit == "" {
+					i.Commit = s.Value
+				}
+			case "vcs.modified":
+				i.Dirty = s.Value == "true"
+			case "vcs.time":
+				if i.BuildTime == "" {
+					i.BuildTime = s.Value
+				}
+			}
+		}
+		for _, dep := range bi.Deps {
+			if dep.Replace != nil {
+				dep = dep.Replace
+			}
+			i.Deps = append(i.Deps, Dep{Path: dep.Path, Version: dep.Version})
+		}
+	}
+
+	for _, v := range []*string{&i.Version, &i.Commit, &i.BuildTime} {
+		if *v == "" {
+			*v = unknown
+		}
+	}
+	return i
+}
@@ -0,0 +1,31 @@
+package buildinfo
+
+import "testing"
+
+func TestReadPrefersLdflags(t *testing.T) {
+	defer func(v, c, b string) { version, commit, buildTime = v, c, b }(version, commit, buildTime)
+	version, commit, buildTime = "v1.2.0", "abc123", "2025-01-02T03:04:05Z"
+
+	i := read()
+
+	if i.Version != "v1.2.0" || i.Commit != "abc123" || i.BuildTime != "2025-01-02T03:04:05Z" {
+		t.Errorf("expected values from ldflags, got %+v", i)
+	}
+	if i.Name != Name || i.GoVersion == "" {
+		t.Errorf("expected name and go version, got %+v", i)
+	}
+}
+
+func TestReadFallsBackToUnknown(t *testing.T) {
+	i := read()
+
+	// test binaries aren't stamped with vcs info
+	for field, value := range map[string]string{"version": i.Version, "commit": i.Commit, "build_time": i.BuildTime} {
+		if value == "" {
+			t.Errorf("expected %s to fall back to a non-empty value", field)
+		}
+	}
+	if Summary() != i.Build {
+		t.Errorf("expected summary to be the build info without deps, got %+v", Summary())
+	}
+}
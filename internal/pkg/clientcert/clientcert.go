@@ -0,0 +1,75 @@
+// Package clientcert carries the identity of the client certificate of a mutual TLS request in its context.
+package clientcert
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"time"
+)
+
+type key string
+
+const (
+	ctxIdentity key = "client_identity"
+)
+
+// Identity is the verified client certificate of a request
+type Identity struct {
+	CommonName   string    `json:"common_name"`
+	Subject      string    `json:"subject"`
+	Issuer       string    `json:"issuer"`
+	SerialNumber string    `json:"serial_number"`
+	DNSNames     []string  `json:"dns_names,omitempty"`
+	URIs         []string  `json:"uris,omitempty"`
+	NotAfter     time.Time `json:"not_after"`
+	// Fingerprint is the hex encoded SHA-256 of the certificate
+	Fingerprint string `json:"fingerprint"`
+}
+
+// New returns the identity of cert
+func New(cert *x509.Certificate) Identity {
+	sum := sha256.Sum256(cert.Raw)
+	id := Identity{
+		CommonName:   cert.Subject.CommonName,
+		Subject:      cert.Subject.String(),
+		Issuer:       cert.Issuer.String(),
+		SerialNumber: cert.SerialNumber.String(),
+		DNSNames:     cert.DNSNames,
+		NotAfter:     cert.NotAfter,
+		Fingerprint:  hex.EncodeToString(sum[:]),
+	}
+	for _, uri := range cert.URIs {
+		id.URIs = append(id.URIs, uri.String())
+	}
+	return id
+}
+
+// Name names the client in the logs: the first URI (e.g. a SPIFFE ID), else the common name, else the first DNS name
+func (id Identity) Name() string {
+	switch {
+	case len(id.URIs) > 0:
+		return id.URIs[0]
+	case id.CommonName != "":
+		return id.CommonName
+	case len(id.DNSNames) > 0:
+		return id.DNSNames[0]
+	default:
+		return id.Subject
+	}
+}
+
+// NewContext returns a copy of ctx carrying id
+func NewContext(ctx context.Context, id Identity) context.Context {
+	return context.WithValue(ctx, ctxIdentity, id)
+}
+
+// FromContext returns the identity carried by ctx, false when the request had no client certificate
+func FromContext(ctx context.Context) (Identity, bool) {
+	if ctx == nil {
+		return Identity{}, false
+	}
+	id, ok := ctx.Value(ctxIdentity).(Identity)
+	return id, ok
+}
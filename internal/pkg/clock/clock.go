@@ -0,0 +1,155 @@
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock tells the current time in a fixed location.
+// Inject it instead of calling time.Now, so time dependent behaviour can be tested with Fake.
+type Clock interface {
+	Now() time.Time
+	Location() *time.Location
+	// NewTimer returns a timer firing once the clock passed Now() + d
+	NewTimer(d time.Duration) Timer
+}
+
+// Timer is a single shot timer of a Clock, like time.Timer
+type Timer interface {
+	// C receives the time of the clock when the timer fires
+	C() <-chan time.Time
+	// Stop prevents the timer from firing, it returns false if the timer already fired or was stopped
+	Stop() bool
+}
+
+type realClock struct {
+	loc *time.Location
+}
+
+// New returns a clock reading the system time, in loc (e.g. the location of server.time_zone)
+func New(loc *time.Location) Clock {
+	if loc == nil {
+		loc = time.Local
+	}
+	return realClock{loc: loc}
+}
+
+func (c realClock) Now() time.Time {
+	return time.Now().In(c.loc)
+}
+
+func (c realClock) Location() *time.Location {
+	return c.loc
+}
+
+func (c realClock) NewTimer(d time.Duration) Timer {
+	return realTimer{time.NewTimer(d)}
+}
+
+type realTimer struct {
+	t *time.Timer
+}
+
+func (t realTimer) C() <-chan time.Time {
+	return t.t.C
+}
+
+func (t realTimer) Stop() bool {
+	return t.t.Stop()
+}
+
+// Fake is a clock that only moves when told to, safe for concurrent use
+type Fake struct {
+	mu     sync.RWMutex
+	now    time.Time
+	timers []*fakeTimer
+}
+
+// NewFake returns a clock stopped at now, its location is the location of now
+func NewFake(now time.Time) *Fake {
+	return &Fake{now: now}
+}
+
+func (c *Fake) Now() time.Time {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.now
+}
+
+func (c *Fake) Location() *time.Location {
+	return c.Now().Location()
+}
+
+// NewTimer returns a timer firing when Set or Advance moves the clock to Now() + d or later,
+// it fires right away when d is not positive
+func (c *Fake) NewTimer(d time.Duration) Timer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	t := &fakeTimer{clock: c, at: c.now.Add(d), c: make(chan time.Time, 1)}
+	if d <= 0 {
+		t.c <- c.now
+		return t
+	}
+	c.timers = append(c.timers, t)
+	return t
+}
+
+// Timers returns the number of timers waiting to fire, so a test can wait for the code under test to set one
+func (c *Fake) Timers() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.timers)
+}
+
+// Set moves the clock to now, the timers due by now fire
+func (c *Fake) Set(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = now
+	c.fire()
+}
+
+// Advance moves the clock forward by d, the timers due by then fire
+func (c *Fake) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	c.fire()
+}
+
+// fire sends the time to the timers due, c.mu must be held
+func (c *Fake) fire() {
+	waiting := c.timers[:0]
+	for _, t := range c.timers {
+		if t.at.After(c.now) {
+			waiting = append(waiting, t)
+			continue
+		}
+		t.c <- c.now
+	}
+	clear(c.timers[len(waiting):])
+	c.timers = waiting
+}
+
+type fakeTimer struct {
+	clock *Fake
+	at    time.Time
+	c     chan time.Time
+}
+
+func (t *fakeTimer) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *fakeTimer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	for i, other := range t.clock.timers {
+		if other == t {
+			t.clock.timers = append(t.clock.timers[:i], t.clock.timers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
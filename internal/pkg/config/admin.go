@@ -0,0 +1,35 @@
+package config
+
+type AdminConfig interface {
+	GetEnabled() bool
+	GetHost() string
+	GetPort() uint
+	GetToken() string
+}
+
+type adminConfig struct {
+	Enabled bool   `yaml:"enabled" mapstructure:"enabled" default:"false" reload:"restart" desc:"Serve the ops endpoints (pprof, expvar, routes, runtime stats, config) on a separate port"`
+	Host    string `yaml:"host" mapstructure:"host" default:"127.0.0.1" reload:"restart" desc:"Interface the admin server listens on, 127.0.0.1 keeps it loopback-only, empty listens on every interface"`
+	Port    uint   `yaml:"port" mapstructure:"port" default:"8001" reload:"restart" validate:"min=1,max=65535" desc:"Port of the admin server, must differ from server.port"`
+	Token   string `yaml:"token" mapstructure:"token" secret:"true" logger:"-" validate:"required_if=Enabled true" desc:"Bearer token required by every admin endpoint, usually a secret reference (file://, env: or enc:)"`
+}
+
+func Admin() AdminConfig {
+	return Current().Admin()
+}
+
+func (a *adminConfig) GetEnabled() bool {
+	return a.Enabled
+}
+
+func (a *adminConfig) GetHost() string {
+	return a.Host
+}
+
+func (a *adminConfig) GetPort() uint {
+	return a.Port
+}
+
+func (a *adminConfig) GetToken() string {
+	return a.Token
+}
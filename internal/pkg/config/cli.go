@@ -0,0 +1,119 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// FlagsUsage documents the precedence of the flags bound by BindFlags, for --help
+const FlagsUsage = "Every config key is a flag, e.g. --server.port, --db.host.\n" +
+	"Precedence, from the highest: flag > env var (APP_SERVER_PORT) > remote config > config overlay > config file > default.\n" +
+	"The flags of secrets only accept secret references, e.g. --db.password env:DB_PASSWORD."
+
+// BindFlags adds a flag for every config key to fs, e.g. --server.port.
+// The help text comes from the `desc` and `default` tags, lists of structs (e.g. logger.logfile_configs) can only be set in files.
+// The flags of secrets (e.g. --db.password) only accept secret references, a value would show in ps and the shell history.
+// Only flags set on the command line override the config, see WithFlags.
+func BindFlags(fs *pflag.FlagSet) {
+	for _, f := range leafFields(reflect.TypeOf(appConfig{}), "") {
+		if isStructSlice(f.Struct.Type) {
+			continue
+		}
+
+		usage := f.Struct.Tag.Get("desc")
+		if env := envName(f.Key); env != "" {
+			usage += fmt.Sprintf(" (env %s)", env)
+		}
+		def := f.Struct.Tag.Get("default")
+
+		if isSecret(f.Struct) {
+			fs.Var(new(secretRefValue), f.Key, usage+" (the flag only takes a secret reference)")
+			continue
+		}
+
+		switch f.Struct.Type.Kind() {
+		case reflect.Bool:
+			b, _ := strconv.ParseBool(def)
+			fs.Bool(f.Key, b, usage)
+		case reflect.Int:
+			n, _ := strconv.Atoi(def)
+			fs.Int(f.Key, n, usage)
+		case reflect.Uint:
+			n, _ := strconv.ParseUint(def, 10, 0)
+			fs.Uint(f.Key, uint(n), usage)
+		case reflect.Slice:
+			fs.StringSlice(f.Key, defaultValue(f.Struct.Type, def).([]string), usage)
+		default:
+			fs.String(f.Key, def, usage)
+		}
+	}
+}
+
+// secretRefValue is the flag of a secret, it rejects anything but a secret reference
+type secretRefValue string
+
+func (s *secretRefValue) String() string {
+	return string(*s)
+}
+
+func (s *secretRefValue) Set(value string) error {
+	if !isRef(value) {
+		return fmt.Errorf("must be a secret reference (file://, env: or enc:), a value would show in ps and the shell history")
+	}
+	if err := checkRef(value); err != nil {
+		return err
+	}
+	*s = secretRefValue(value)
+	return nil
+}
+
+func (s *secretRefValue) Type() string {
+	return "ref"
+}
+
+// WithFlags overrides config keys with the flags of fs set on the command line, fs must be bound with BindFlags and parsed
+func WithFlags(fs *pflag.FlagSet) LoaderOption {
+	return func(l *Loader) {
+		l.flags = fs
+	}
+}
+
+// applyFlags overrides every key whose flag was set, after applyEnv so flags take precedence over env vars
+func (l *Loader) applyFlags(v *viper.Viper) {
+	if l.flags == nil {
+		return
+	}
+	l.flags.Visit(func(flag *pflag.Flag) {
+		if _, ok := configFlag(flag.Name); !ok {
+			return
+		}
+		if slice, ok := flag.Value.(pflag.SliceValue); ok {
+			v.Set(flag.Name, slice.GetSlice())
+			return
+		}
+		v.Set(flag.Name, flag.Value.String())
+	})
+}
+
+// flagSet reports whether the flag of key was set on the command line
+func (l *Loader) flagSet(key string) bool {
+	if l.flags == nil {
+		return false
+	}
+	flag := l.flags.Lookup(key)
+	return flag != nil && flag.Changed
+}
+
+// configFlag returns the config field bound to the flag name, flags defined by the caller (e.g. --configpath) aren't config keys
+func configFlag(name string) (field, bool) {
+	for _, f := range leafFields(reflect.TypeOf(appConfig{}), "") {
+		if f.Key == name && !isStructSlice(f.Struct.Type) {
+			return f, true
+		}
+	}
+	return field{}, false
+}
@@ -2,38 +2,129 @@ package config
 
 import (
 	"fmt"
-	"strings"
+	"os"
+	"sync/atomic"
 
-	"github.com/spf13/viper"
+	"starter-go/internal/pkg/flags"
+	"starter-go/internal/pkg/logger"
 )
 
+// envPrefix is prepended to env vars overriding config keys, e.g. server.port is APP_SERVER_PORT
+const envPrefix = "APP"
+
 type appConfig struct {
-	Server serverConfig `yaml:"server" mapstructure:"server"`
-	Logger loggerConfig `yaml:"logger" mapstructure:"logger"`
-	DB     databaseConfig `yaml:"db" mapstructure:"db"`
+	Server    serverConfig    `yaml:"server" mapstructure:"server" desc:"HTTP server"`
+	Logger    loggerConfig    `yaml:"logger" mapstructure:"logger" desc:"Log outputs, the level is set by server.loglevel"`
+	Storage   storageConfig   `yaml:"storage" mapstructure:"storage" desc:"Where the repositories store their data"`
+	DB        databaseConfig  `yaml:"db" mapstructure:"db" desc:"Database of the mysql and postgres storage backends"`
+	CORS      corsConfig      `yaml:"cors" mapstructure:"cors" desc:"Cross-origin resource sharing"`
+	Health    healthConfig    `yaml:"health" mapstructure:"health" desc:"Readiness checks of /healthz/ready"`
+	Scheduler schedulerConfig `yaml:"scheduler" mapstructure:"scheduler" desc:"Scheduled jobs"`
+	Admin     adminConfig     `yaml:"admin" mapstructure:"admin" desc:"Admin server of the ops endpoints, separate from the public port"`
+	Timeout   timeoutConfig   `yaml:"timeout" mapstructure:"timeout" desc:"Timeouts of the requests, their context is canceled once over"`
+	RateLimit rateLimitConfig `yaml:"ratelimit" mapstructure:"ratelimit" desc:"Rate limits of the requests, per client"`
+	Remote    remoteConfig    `yaml:"remote" mapstructure:"remote" desc:"Remote config document, polled for changes"`
+	Flags     []flagConfig    `yaml:"flags" mapstructure:"flags" validate:"unique=Name,dive" desc:"Feature flags, see the flags package"`
+
+	// keys whose value was resolved from a secret reference, along with the reference (see refName)
+	resolved map[string]string
+	// where the value of every key was read from
+	sources map[string]Source
+	// config files that were read, the base config followed by the environment overlay if any
+	files []string
 }
 
-var cfg = new(appConfig)
+// Config is a loaded configuration, created by Loader.Load.
+// It is never modified afterwards, a reload creates a new Config, so it can be shared between goroutines.
+type Config struct {
+	c *appConfig
+}
 
-func Init(path string) error {
-	fmt.Printf("reading config path: %s\n", path)
-	
-	viper.SetConfigFile(path)
-	viper.SetConfigType("yaml")
+func (cfg *Config) Server() ServerConfig {
+	return &cfg.c.Server
+}
 
-	// Set a prefix for environment variables, so we can override config with APP_ prefixed env vars.
-	viper.SetEnvPrefix("APP")
-	viper.AutomaticEnv()
-	// a.b.c will be APP_A_B_C
-	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+func (cfg *Config) Database() DatabaseConfig {
+	return &cfg.c.DB
+}
 
-	if err := viper.ReadInConfig(); err != nil {
-		return fmt.Errorf("error reading config file, %s", err)
-	}
+func (cfg *Config) Storage() StorageConfig {
+	return &cfg.c.Storage
+}
+
+func (cfg *Config) Health() HealthConfig {
+	return &cfg.c.Health
+}
+
+func (cfg *Config) Scheduler() SchedulerConfig {
+	return &cfg.c.Scheduler
+}
+
+func (cfg *Config) Admin() AdminConfig {
+	return &cfg.c.Admin
+}
+
+func (cfg *Config) Timeout() TimeoutConfig {
+	return &cfg.c.Timeout
+}
+
+func (cfg *Config) RateLimit() RateLimitConfig {
+	return &cfg.c.RateLimit
+}
+
+func (cfg *Config) CORS() CORSConfig {
+	return &cfg.c.CORS
+}
+
+func (cfg *Config) Environment() Environment {
+	return cfg.c.Server.Environment
+}
+
+// Logger returns the logger section, along with the level from server.loglevel
+func (cfg *Config) Logger() logger.LogConfig {
+	return cfg.c.logConfig()
+}
+
+// Flags returns the feature flags section
+func (cfg *Config) Flags() []flags.Flag {
+	return cfg.c.featureFlags()
+}
+
+var (
+	// current is swapped as a whole on reload, so readers never see a half applied config
+	current atomic.Pointer[Config]
+	// loader used by Init, reused on reload
+	defaultLoader *Loader
+)
+
+func init() {
+	current.Store(&Config{c: new(appConfig)})
+}
+
+// Current returns the configuration loaded by Init, or the latest one after a reload.
+// The package level accessors (Server(), Database(), ...) are shortcuts to Current().
+func Current() *Config {
+	return current.Load()
+}
+
+func get() *appConfig {
+	return current.Load().c
+}
+
+// Init loads the config with NewLoader(path, opts...) and makes it the current one, see Loader.Load.
+// When validation fails, the returned *ValidationError lists every invalid key.
+func Init(path string, opts ...LoaderOption) error {
+	// stderr keeps stdout clean for commands printing the config
+	fmt.Fprintf(os.Stderr, "reading config path: %s\n", path)
 
-	if err := viper.Unmarshal(cfg); err != nil {
-		return fmt.Errorf("unable to decode into struct, %s", err)
+	loader := NewLoader(path, opts...)
+	loaded, remote, err := loader.load()
+	if err != nil {
+		return err
 	}
 
+	defaultLoader = loader
+	current.Store(&Config{c: loaded})
+	loader.remote.commit(remote)
 	return nil
 }
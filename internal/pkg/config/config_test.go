@@ -0,0 +1,811 @@
+package config
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/spf13/pflag"
+
+	"starter-go/internal/pkg/flags"
+	"starter-go/internal/pkg/logger"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// resetSubscribers removes the subscribers registered by a test once it completed, they're package globals
+func resetSubscribers(t *testing.T) {
+	t.Cleanup(func() {
+		subsMu.Lock()
+		defer subsMu.Unlock()
+		subscribers = map[Section][]func(){}
+	})
+}
+
+func TestInitAppliesDefaults(t *testing.T) {
+	path := writeConfig(t, `
+server:
+  port: 9000
+logger:
+  enable_logfile: true
+  logfile_configs:
+    - levels: [info]
+      fullpath_filename: `+filepath.Join(t.TempDir(), "log", "data.log")+`
+`)
+
+	if err := Init(path); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if Server().GetPort() != 9000 {
+		t.Errorf("expected port 9000, got %d", Server().GetPort())
+	}
+	if Server().GetIdleTimeout() != 60000 {
+		t.Errorf("expected default idle timeout 60000, got %d", Server().GetIdleTimeout())
+	}
+	if Server().GetTimeZone() != "UTC" {
+		t.Errorf("expected default time zone UTC, got %s", Server().GetTimeZone())
+	}
+	if got := LoggerConfig().LogFileConfigs[0].MaxSize; got != 500 {
+		t.Errorf("expected default max_size 500, got %d", got)
+	}
+}
+
+func TestDatabasePortDefaultsToBackend(t *testing.T) {
+	for backend, expected := range map[string]uint{"mysql": 3306, "postgres": 5432} {
+		cfg, err := NewLoader(writeConfig(t, "storage:\n  backend: "+backend+"\n")).Load()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := cfg.Database().GetPort(); got != expected {
+			t.Errorf("expected %s port %d, got %d", backend, expected, got)
+		}
+	}
+
+	cfg, err := NewLoader(writeConfig(t, "storage:\n  backend: postgres\ndb:\n  port: 6432\n")).Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := cfg.Database().GetPort(); got != 6432 {
+		t.Errorf("expected the configured port to be kept, got %d", got)
+	}
+}
+
+func TestInitAggregatesErrors(t *testing.T) {
+	path := writeConfig(t, `
+server:
+  time_zone: Mars/Olympus_Mons
+  env: qa
+  tls:
+    enabled: true
+    cipher_suites: [TLS_RSA_WITH_RC4_128_SHA]
+    client_auth: required
+logger:
+  enable_stdout: false
+  enable_logfile: false
+admin:
+  enabled: true
+`)
+	t.Setenv("APP_SERVER_PORT", "70000")
+
+	err := Init(path)
+
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("expected *ValidationError, got %v", err)
+	}
+
+	got := map[string]string{}
+	for _, fe := range verr.Errors {
+		got[fe.Key] = fe.Env
+	}
+
+	expected := map[string]string{
+		"server.time_zone":     "APP_SERVER_TIME_ZONE",
+		"server.env":           "APP_SERVER_ENV",
+		"server.port":          "APP_SERVER_PORT",
+		"logger.enable_stdout": "APP_LOGGER_ENABLE_STDOUT",
+		"admin.token":          "APP_ADMIN_TOKEN",
+		"server.tls.cert_file": "APP_SERVER_TLS_CERT_FILE",
+		"server.tls.client_ca": "APP_SERVER_TLS_CLIENT_CA",
+		// insecure suites aren't accepted
+		"server.tls.cipher_suites[0]": "",
+	}
+	for key, env := range expected {
+		if gotEnv, ok := got[key]; !ok || gotEnv != env {
+			t.Errorf("expected error for %s (%s), got %v", key, env, verr)
+		}
+	}
+}
+
+func TestReload(t *testing.T) {
+	resetSubscribers(t)
+
+	path := writeConfig(t, `
+server:
+  port: 8000
+  loglevel: INFO
+cors:
+  allow_origins: ["https://a.example.com"]
+`)
+	if err := Init(path); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var notified []string
+	OnLoggerChange(func(conf logger.LogConfig) {
+		notified = append(notified, "logger")
+		if conf.Level != logger.DEBUG {
+			t.Errorf("expected DEBUG level, got %d", conf.Level)
+		}
+	})
+	OnCORSChange(func(CORSConfig) { notified = append(notified, "cors") })
+
+	if err := os.WriteFile(path, []byte(`
+server:
+  port: 9000
+  loglevel: DEBUG
+cors:
+  allow_origins: ["https://a.example.com"]
+`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := Reload(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if Server().GetLoglevel() != "DEBUG" {
+		t.Errorf("expected loglevel to be reloaded, got %s", Server().GetLoglevel())
+	}
+	if Server().GetPort() != 8000 {
+		t.Errorf("expected restart-only port to be kept, got %d", Server().GetPort())
+	}
+	if len(notified) != 1 || notified[0] != "logger" {
+		t.Errorf("expected only the logger subscriber to be notified, got %v", notified)
+	}
+
+	// invalid config is rejected and the current one is kept
+	if err := os.WriteFile(path, []byte("server:\n  loglevel: LOUD\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := Reload(); err == nil {
+		t.Fatal("expected invalid config to be rejected")
+	}
+	if Server().GetLoglevel() != "DEBUG" {
+		t.Errorf("expected current config to be kept, got %s", Server().GetLoglevel())
+	}
+}
+
+func TestReloadValidatesRestartOnlyKeys(t *testing.T) {
+	resetSubscribers(t)
+
+	path := writeConfig(t, "server:\n  shutdown_grace: 20000\n  shutdown_timeout: 30000\n")
+	if err := Init(path); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// valid on its own, but shutdown_grace is restart-only so 20000 is kept, above the new timeout
+	if err := os.WriteFile(path, []byte("server:\n  shutdown_grace: 5000\n  shutdown_timeout: 10000\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	var verr *ValidationError
+	if err := Reload(); !errors.As(err, &verr) || !verr.has("server.shutdown_grace") {
+		t.Fatalf("expected shutdown_grace error, got %v", err)
+	}
+	if Server().GetShutdownTimeout() != 30000 {
+		t.Errorf("expected current config to be kept, got shutdown_timeout %d", Server().GetShutdownTimeout())
+	}
+}
+
+func TestAdminPortDiffersFromServerPort(t *testing.T) {
+	load := func(yaml string) error {
+		_, err := NewLoader(writeConfig(t, yaml), WithLookupEnv(func(string) (string, bool) { return "", false })).Load()
+		return err
+	}
+
+	var verr *ValidationError
+	err := load("server:\n  port: 8001\nadmin:\n  enabled: true\n  token: hunter2\n")
+	if !errors.As(err, &verr) || !verr.has("admin.port") {
+		t.Fatalf("expected admin.port error, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "must differ from server.port") {
+		t.Errorf("unexpected message: %v", err)
+	}
+
+	// disabled, or the public server isn't on a TCP port
+	if err := load("server:\n  port: 8001\n"); err != nil {
+		t.Errorf("unexpected error with the admin server disabled: %v", err)
+	}
+	if err := load("server:\n  port: 8001\n  listener:\n    network: unix\n    socket_path: /tmp/app.sock\nadmin:\n  enabled: true\n  token: hunter2\n"); err != nil {
+		t.Errorf("unexpected error with a unix socket: %v", err)
+	}
+}
+
+func TestRateLimitAPIKeyRequiresKeys(t *testing.T) {
+	load := func(yaml string) (*Config, error) {
+		return NewLoader(writeConfig(t, yaml), WithLookupEnv(func(name string) (string, bool) {
+			return "secret-key", name == "PARTNER_KEY"
+		})).Load()
+	}
+	policy := "ratelimit:\n  policies:\n    - path: /api/v1\n      limit: 10\n      key_by: api_key\n"
+
+	var verr *ValidationError
+	if _, err := load(policy); !errors.As(err, &verr) || !verr.has("ratelimit.policies[0].key_by") {
+		t.Fatalf("expected ratelimit.policies[0].key_by error, got %v", err)
+	}
+
+	cfg, err := load(policy + "  api_keys:\n    - name: partner\n      key: env:PARTNER_KEY\n")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if name, ok := cfg.RateLimit().GetAPIKeys().Lookup("secret-key"); !ok || name != "partner" {
+		t.Errorf("expected the resolved key of partner, got %q, %v", name, ok)
+	}
+	if got := cfg.Effective().Config["ratelimit"].(map[string]interface{})["api_keys"].([]interface{})[0].(map[string]interface{})["key"]; got != redacted {
+		t.Errorf("expected the API key to be redacted, got %v", got)
+	}
+}
+
+func TestFlagsRolloutByUserIDRequiresClientAuth(t *testing.T) {
+	load := func(yaml string) error {
+		_, err := NewLoader(writeConfig(t, yaml), WithLookupEnv(func(string) (string, bool) { return "", false })).Load()
+		return err
+	}
+	flag := "flags:\n  - name: beta\n    enabled: true\n    rollout: 10\n    rollout_by: user_id\n"
+
+	var verr *ValidationError
+	if err := load(flag); !errors.As(err, &verr) || !verr.has("flags[0].rollout_by") {
+		t.Fatalf("expected flags[0].rollout_by error, got %v", err)
+	}
+	mtls := "server:\n  tls:\n    enabled: true\n    cert_file: server.pem\n    key_file: server.key\n    client_auth: optional\n    client_ca: ca.pem\n"
+	if err := load(mtls + flag); err != nil {
+		t.Errorf("unexpected error with mutual TLS: %v", err)
+	}
+}
+
+func TestInitResolvesSecrets(t *testing.T) {
+	key, err := GenerateSecretKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("APP_CONFIG_KEY", key)
+	rawKey, err := LoadSecretKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	encrypted, err := EncryptSecret("from-enc", rawKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	secretFile := filepath.Join(t.TempDir(), "db_user")
+	if err := os.WriteFile(secretFile, []byte("from-file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("TEST_DB_PASSWORD", "from-env")
+
+	path := writeConfig(t, `
+db:
+  user: file://`+secretFile+`
+  password: env:TEST_DB_PASSWORD
+  name: `+encrypted+`
+`)
+	if err := Init(path); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if Database().GetUser() != "from-file" {
+		t.Errorf("expected user from file, got %q", Database().GetUser())
+	}
+	if Database().GetPassword() != "from-env" {
+		t.Errorf("expected password from env, got %q", Database().GetPassword())
+	}
+	if Database().GetName() != "from-enc" {
+		t.Errorf("expected name from encrypted value, got %q", Database().GetName())
+	}
+
+	values := flatten(get())
+	for _, key := range []string{"db.user", "db.password", "db.name"} {
+		if got := values[key].Display(); got != redacted {
+			t.Errorf("expected %s to be redacted, got %q", key, got)
+		}
+	}
+}
+
+func TestInitReportsUnresolvedSecrets(t *testing.T) {
+	path := writeConfig(t, "db:\n  password: env:TEST_MISSING_PASSWORD\n")
+
+	var verr *ValidationError
+	if err := Init(path); !errors.As(err, &verr) || !verr.has("db.password") {
+		t.Fatalf("expected db.password error, got %v", err)
+	}
+}
+
+func TestEffectiveConfigSources(t *testing.T) {
+	path := writeConfig(t, "server:\n  port: 9000\ndb:\n  password: env:TEST_DB_PASSWORD\n")
+	t.Setenv("APP_DB_HOST", "db.internal")
+	t.Setenv("TEST_DB_PASSWORD", "hunter2")
+
+	if err := Init(path); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	effective := EffectiveConfig()
+
+	expected := map[string]string{
+		"server.port":      "file:" + path,
+		"server.time_zone": "default",
+		"db.host":          "env:APP_DB_HOST",
+		"db.password":      "file:" + path + " (env:TEST_DB_PASSWORD)",
+	}
+	for key, source := range expected {
+		if effective.Sources[key] != source {
+			t.Errorf("expected %s source %q, got %q", key, source, effective.Sources[key])
+		}
+	}
+
+	out, err := effective.Marshal("json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(out), "hunter2") {
+		t.Errorf("expected password to be redacted, got %s", out)
+	}
+}
+
+func TestConfigHash(t *testing.T) {
+	load := func(content string) *Config {
+		t.Helper()
+		cfg, err := NewLoader(writeConfig(t, content)).Load()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return cfg
+	}
+
+	base := load("server:\n  port: 9000\nadmin:\n  enabled: true\n  token: hunter2\n")
+	same := load("admin:\n  token: hunter2\n  enabled: true\nserver:\n  port: 9000\n")
+	rotated := load("server:\n  port: 9000\nadmin:\n  enabled: true\n  token: hunter3\n")
+	changed := load("server:\n  port: 9001\nadmin:\n  enabled: true\n  token: hunter2\n")
+
+	if base.Hash() != same.Hash() {
+		t.Error("expected the same config to have the same hash")
+	}
+	if base.Hash() != rotated.Hash() {
+		t.Error("expected secrets not to change the hash")
+	}
+	if base.Hash() == changed.Hash() {
+		t.Error("expected a changed key to change the hash")
+	}
+}
+
+func TestInitLayersEnvironmentOverlay(t *testing.T) {
+	path := writeConfig(t, "server:\n  env: staging\n  loglevel: INFO\n  port: 9000\n")
+	overlay := overlayPath(path, EnvStaging)
+	if err := os.WriteFile(overlay, []byte("server:\n  loglevel: WARN\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Init(path); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if CurrentEnvironment() != EnvStaging {
+		t.Errorf("expected staging environment, got %s", CurrentEnvironment())
+	}
+	if Server().GetLoglevel() != "WARN" {
+		t.Errorf("expected loglevel from overlay, got %s", Server().GetLoglevel())
+	}
+	if Server().GetPort() != 9000 {
+		t.Errorf("expected port from base config, got %d", Server().GetPort())
+	}
+	if got := EffectiveConfig().Sources["server.loglevel"]; got != "file:"+overlay {
+		t.Errorf("expected loglevel source to be the overlay, got %s", got)
+	}
+
+	// env var selects the environment, so another overlay (none here) is used
+	t.Setenv("APP_SERVER_ENV", "production")
+	if err := Init(path); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if Server().GetLoglevel() != "INFO" {
+		t.Errorf("expected loglevel from base config, got %s", Server().GetLoglevel())
+	}
+}
+
+func TestEnvironmentIsDevelopment(t *testing.T) {
+	for env, expected := range map[Environment]bool{
+		EnvLocal:       false,
+		EnvDevelopment: true,
+		EnvStaging:     false,
+		EnvProduction:  false,
+		"":             false,
+	} {
+		if got := env.IsDevelopment(); got != expected {
+			t.Errorf("expected IsDevelopment of %q to be %v, got %v", env, expected, got)
+		}
+	}
+}
+
+func TestLoaderIsolation(t *testing.T) {
+	path := writeConfig(t, "server:\n  port: 9000\n")
+
+	for _, port := range []string{"8001", "8002"} {
+		t.Run(port, func(t *testing.T) {
+			t.Parallel()
+
+			env := map[string]string{"APP_SERVER_PORT": port}
+			loader := NewLoader(path, WithLookupEnv(func(key string) (string, bool) {
+				value, ok := env[key]
+				return value, ok
+			}))
+
+			cfg, err := loader.Load()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := fmt.Sprint(cfg.Server().GetPort()); got != port {
+				t.Errorf("expected port %s, got %s", port, got)
+			}
+			if got := cfg.Effective().Sources["server.port"]; got != "env:APP_SERVER_PORT" {
+				t.Errorf("expected port from env, got %s", got)
+			}
+		})
+	}
+}
+
+func TestLoadRejectsUnknownKeys(t *testing.T) {
+	path := writeConfig(t, "server:\n  port: 9000\nlogger:\n  enable_logfiles: true\n  logfile_configs:\n    - levels: [info]\n      max_sizes: 10\n")
+
+	_, err := NewLoader(path).Load()
+
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("expected *ValidationError, got %v", err)
+	}
+
+	expected := map[string]int{
+		"logger.enable_logfiles":              4,
+		"logger.logfile_configs[0].max_sizes": 7,
+	}
+	for _, fe := range verr.Errors {
+		if line, ok := expected[fe.Key]; ok {
+			if fe.File != path || fe.Line != line {
+				t.Errorf("expected %s at %s:%d, got %s:%d", fe.Key, path, line, fe.File, fe.Line)
+			}
+			delete(expected, fe.Key)
+		}
+	}
+	if len(expected) > 0 {
+		t.Errorf("expected unknown keys %v to be reported, got %v", expected, verr)
+	}
+}
+
+func TestValidationErrorLines(t *testing.T) {
+	path := writeConfig(t, "server:\n  loglevel: LOUD\n  port: 9000\n")
+
+	_, err := NewLoader(path).Load()
+
+	var verr *ValidationError
+	if !errors.As(err, &verr) || len(verr.Errors) != 1 {
+		t.Fatalf("expected a single error, got %v", err)
+	}
+	if got := verr.Errors[0].String(); !strings.HasPrefix(got, path+":2: server.loglevel") {
+		t.Errorf("expected error at line 2, got %s", got)
+	}
+}
+
+// the committed schema is used by editors, it must match the config structs
+func TestSchemaIsUpToDate(t *testing.T) {
+	schema, err := Schema()
+	if err != nil {
+		t.Fatal(err)
+	}
+	committed, err := os.ReadFile("../../../config/config.schema.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.TrimSpace(string(committed)) != strings.TrimSpace(string(schema)) {
+		t.Error("config/config.schema.json is outdated, run: go run ./cmd config schema > config/config.schema.json")
+	}
+}
+
+func TestReloadFlags(t *testing.T) {
+	resetSubscribers(t)
+
+	path := writeConfig(t, "flags:\n  - name: new_create\n    rollout: 10\n")
+	if err := Init(path); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := Flags(); len(got) != 1 || got[0].Enabled || got[0].RolloutBy != "client_ip" {
+		t.Fatalf("expected a disabled flag with defaults, got %+v", got)
+	}
+
+	var reloaded []flags.Flag
+	OnFlagsChange(func(f []flags.Flag) { reloaded = f })
+
+	if err := os.WriteFile(path, []byte("flags:\n  - name: new_create\n    enabled: true\n    rollout: 10\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := Reload(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(reloaded) != 1 || !reloaded[0].Enabled {
+		t.Errorf("expected enabled flag to be notified, got %+v", reloaded)
+	}
+
+	if err := os.WriteFile(path, []byte("flags:\n  - name: a\n  - name: a\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	var verr *ValidationError
+	if err := Reload(); !errors.As(err, &verr) || !verr.has("flags") {
+		t.Errorf("expected duplicate names to be rejected, got %v", err)
+	}
+}
+
+// remoteServer serves a signed config document, with ETag support
+type remoteServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	body     string
+	secret   string
+	requests int
+	notMod   int
+}
+
+func newRemoteServer(t *testing.T, body, secret string) *remoteServer {
+	rs := &remoteServer{body: body, secret: secret}
+	rs.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rs.mu.Lock()
+		defer rs.mu.Unlock()
+		rs.requests++
+
+		etag := fmt.Sprintf("%q", hex.EncodeToString(SignRemoteConfig([]byte(rs.body), nil))[:16])
+		if r.Header.Get("If-None-Match") == etag {
+			rs.notMod++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		w.Header().Set(signatureHeader, hex.EncodeToString(SignRemoteConfig([]byte(rs.body), []byte(rs.secret))))
+		_, _ = w.Write([]byte(rs.body))
+	}))
+	t.Cleanup(rs.Close)
+	return rs
+}
+
+func (rs *remoteServer) set(body string) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	rs.body = body
+}
+
+func TestRemoteConfig(t *testing.T) {
+	rs := newRemoteServer(t, `{"server": {"loglevel": "WARN"}}`, "shared")
+	cache := filepath.Join(t.TempDir(), "remote.json")
+	path := writeConfig(t, fmt.Sprintf("server:\n  loglevel: INFO\n  port: 9000\nremote:\n  url: %s\n  secret: env:TEST_REMOTE_SECRET\n  cache_path: %s\n", rs.URL, cache))
+	t.Setenv("TEST_REMOTE_SECRET", "shared")
+
+	if err := Init(path); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if Server().GetLoglevel() != "WARN" {
+		t.Errorf("expected loglevel from remote config, got %s", Server().GetLoglevel())
+	}
+	if got := EffectiveConfig().Sources["server.loglevel"]; got != "remote:"+rs.URL {
+		t.Errorf("expected loglevel source to be the remote config, got %s", got)
+	}
+
+	// unchanged document isn't downloaded again
+	changed, err := defaultLoader.remote.poll()
+	if err != nil || changed || rs.notMod != 1 {
+		t.Errorf("expected a not modified poll, got changed=%v err=%v not modified=%d", changed, err, rs.notMod)
+	}
+
+	// changes go through the reload path
+	rs.set("server:\n  loglevel: ERROR\n")
+	if changed, err := defaultLoader.remote.poll(); err != nil || !changed {
+		t.Fatalf("expected a changed poll, got changed=%v err=%v", changed, err)
+	}
+	if err := Reload(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if Server().GetLoglevel() != "ERROR" {
+		t.Errorf("expected reloaded loglevel, got %s", Server().GetLoglevel())
+	}
+
+	// invalid values are rejected with their line in the remote document
+	rs.set("server:\n  port: 9000\n  loglevel: LOUD\n")
+	var verr *ValidationError
+	if err := Reload(); !errors.As(err, &verr) || verr.Errors[0].File != rs.URL || verr.Errors[0].Line != 3 {
+		t.Errorf("expected server.loglevel error at %s:3, got %v", rs.URL, err)
+	}
+
+	// the rejected document isn't the last applied one, it's still reported as changed and not sent as If-None-Match
+	if changed, err := defaultLoader.remote.poll(); err != nil || !changed {
+		t.Errorf("expected the rejected document to be reported as changed, got changed=%v err=%v", changed, err)
+	}
+	rs.set("server:\n  loglevel: ERROR\n")
+	if changed, err := defaultLoader.remote.poll(); err != nil || changed {
+		t.Errorf("expected the fixed document to match the applied one, got changed=%v err=%v", changed, err)
+	}
+	if err := Reload(); err != nil {
+		t.Errorf("expected the reload to keep the applied document, got %v", err)
+	}
+
+	// oversized documents are rejected
+	rs.set("server:\n  loglevel: ERROR\n#" + strings.Repeat("x", maxRemoteSize) + "\n")
+	if _, err := defaultLoader.remote.poll(); err == nil || !strings.Contains(err.Error(), "larger than") {
+		t.Errorf("expected an oversized document to be rejected, got %v", err)
+	}
+
+	// offline startup uses the cache, it holds the last valid document
+	rs.Close()
+	cfg, err := NewLoader(path).Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Server().GetLoglevel() != "ERROR" {
+		t.Errorf("expected loglevel from the cached document, got %s", cfg.Server().GetLoglevel())
+	}
+}
+
+func TestRemoteConfigRejectedByReload(t *testing.T) {
+	resetSubscribers(t)
+
+	rs := newRemoteServer(t, "server:\n  loglevel: WARN\n", "shared")
+	cache := filepath.Join(t.TempDir(), "remote.json")
+	path := writeConfig(t, fmt.Sprintf("server:\n  shutdown_grace: 20000\n  shutdown_timeout: 30000\nremote:\n  url: %s\n  secret: shared\n  cache_path: %s\n", rs.URL, cache))
+	if err := Init(path); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// valid on its own, rejected by Reload since shutdown_grace is restart-only and stays above the new timeout
+	rs.set("server:\n  loglevel: WARN\n  shutdown_grace: 5000\n  shutdown_timeout: 10000\n")
+	if changed, err := defaultLoader.remote.poll(); err != nil || !changed {
+		t.Fatalf("expected a changed poll, got changed=%v err=%v", changed, err)
+	}
+	var verr *ValidationError
+	if err := Reload(); !errors.As(err, &verr) || !verr.has("server.shutdown_grace") {
+		t.Fatalf("expected shutdown_grace error, got %v", err)
+	}
+
+	// the rejected document isn't committed, it's still reported as changed
+	if changed, err := defaultLoader.remote.poll(); err != nil || !changed {
+		t.Errorf("expected the rejected document to be reported as changed, got changed=%v err=%v", changed, err)
+	}
+
+	// and it isn't cached, offline startup uses the accepted one
+	rs.Close()
+	cfg, err := NewLoader(path).Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := cfg.Server().GetShutdownTimeout(); got != 30000 {
+		t.Errorf("expected shutdown_timeout of the accepted document, got %d", got)
+	}
+}
+
+func TestRemoteConfigRejectsBadSignature(t *testing.T) {
+	rs := newRemoteServer(t, "server:\n  loglevel: WARN\n", "other")
+	path := writeConfig(t, fmt.Sprintf("remote:\n  url: %s\n  secret: shared\n", rs.URL))
+
+	if _, err := NewLoader(path).Load(); err == nil || !strings.Contains(err.Error(), "signature mismatch") {
+		t.Errorf("expected signature mismatch, got %v", err)
+	}
+}
+
+func TestLoaderFlags(t *testing.T) {
+	path := writeConfig(t, "server:\n  port: 9000\n  loglevel: WARN\n")
+
+	fs := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	fs.String("configpath", "", "not a config key")
+	BindFlags(fs)
+	if err := fs.Parse([]string{"--configpath", path, "--server.port=9100", "--cors.allow_origins", "https://a.com,https://b.com"}); err != nil {
+		t.Fatal(err)
+	}
+
+	env := map[string]string{"APP_SERVER_PORT": "8001"}
+	cfg, err := NewLoader(path, WithFlags(fs), WithLookupEnv(func(key string) (string, bool) {
+		value, ok := env[key]
+		return value, ok
+	})).Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if cfg.Server().GetPort() != 9100 {
+		t.Errorf("expected port from flag to take precedence over env, got %d", cfg.Server().GetPort())
+	}
+	if cfg.Server().GetLoglevel() != "WARN" {
+		t.Errorf("expected unset flag to keep loglevel from file, got %s", cfg.Server().GetLoglevel())
+	}
+	if got := cfg.CORS().GetAllowOrigins(); len(got) != 2 || got[1] != "https://b.com" {
+		t.Errorf("expected origins from flag, got %v", got)
+	}
+	if got := cfg.Effective().Sources["server.port"]; got != "flag:--server.port" {
+		t.Errorf("expected port source to be the flag, got %s", got)
+	}
+}
+
+func TestLoaderSecretFlags(t *testing.T) {
+	path := writeConfig(t, "server:\n  port: 9000\n")
+
+	newFlags := func() *pflag.FlagSet {
+		fs := pflag.NewFlagSet("test", pflag.ContinueOnError)
+		fs.SetOutput(io.Discard)
+		BindFlags(fs)
+		return fs
+	}
+	for _, arg := range []string{"--db.password=hunter2", "--admin.token=hunter2", "--remote.secret=hunter2", "--db.password=env:"} {
+		if err := newFlags().Parse([]string{arg}); err == nil {
+			t.Errorf("expected %s to be rejected", arg)
+		}
+	}
+
+	fs := newFlags()
+	if err := fs.Parse([]string{"--db.password=env:DB_PASSWORD"}); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := NewLoader(path, WithFlags(fs), WithLookupEnv(func(key string) (string, bool) {
+		return "from-env", key == "DB_PASSWORD"
+	})).Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := cfg.Database().GetPassword(); got != "from-env" {
+		t.Errorf("expected the password of the reference, got %q", got)
+	}
+}
+
+func TestLoaderOffline(t *testing.T) {
+	// neither the remote URL nor the env var of the secret exist where the files are validated
+	path := writeConfig(t, "db:\n  password: env:TEST_MISSING_PASSWORD\nremote:\n  url: http://127.0.0.1:1/config.yaml\n  secret: env:TEST_MISSING_SECRET\n")
+
+	cfg, err := NewLoader(path, WithOffline()).Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := cfg.Effective().Config["db"].(map[string]interface{})["password"]; got != redacted {
+		t.Errorf("expected the unresolved reference to be redacted, got %v", got)
+	}
+
+	path = writeConfig(t, "db:\n  password: enc:not base64\n")
+	var verr *ValidationError
+	if _, err := NewLoader(path, WithOffline()).Load(); !errors.As(err, &verr) || !verr.has("db.password") {
+		t.Errorf("expected a malformed reference to be reported, got %v", err)
+	}
+}
+
+func TestLoaderLoadServer(t *testing.T) {
+	// the invalid and unresolvable keys outside the listen address don't matter
+	path := writeConfig(t, "server:\n  port: 9000\n  loglevel: LOUD\ndb:\n  password: env:TEST_MISSING_PASSWORD\nremote:\n  url: http://127.0.0.1:1/config.yaml\n")
+
+	server, err := NewLoader(path, WithLookupEnv(func(key string) (string, bool) {
+		if key == "APP_SERVER_TLS_ENABLED" {
+			return "true", true
+		}
+		return "", false
+	})).LoadServer()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if server.GetPort() != 9000 || !server.GetTLS().GetEnabled() {
+		t.Errorf("expected port from the file and TLS from env, got port %d, tls %v", server.GetPort(), server.GetTLS().GetEnabled())
+	}
+}
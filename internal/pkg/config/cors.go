@@ -0,0 +1,19 @@
+package config
+
+import "slices"
+
+type CORSConfig interface {
+	GetAllowOrigins() []string
+}
+
+type corsConfig struct {
+	AllowOrigins []string `yaml:"allow_origins" mapstructure:"allow_origins" default:"*" validate:"min=1,dive,required" desc:"Origins allowed to make cross-origin requests, * allows any origin"`
+}
+
+func CORS() CORSConfig {
+	return Current().CORS()
+}
+
+func (c *corsConfig) GetAllowOrigins() []string {
+	return slices.Clone(c.AllowOrigins)
+}
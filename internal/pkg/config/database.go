@@ -6,18 +6,33 @@ type DatabaseConfig interface {
 	GetUser() string
 	GetPassword() string
 	GetName() string
+	GetSSLMode() string
 }
 
 type databaseConfig struct {
-	Host     string `yaml:"host" mapstructure:"host"`
-	Port     uint   `yaml:"port" mapstructure:"port"`
-	User     string `yaml:"user" mapstructure:"user"`
-	Password string `yaml:"password" mapstructure:"password"`
-	Name     string `yaml:"name" mapstructure:"name"`
+	Host     string `yaml:"host" mapstructure:"host" default:"localhost" reload:"restart" validate:"required" desc:"Database host"`
+	Port     uint   `yaml:"port" mapstructure:"port" default:"0" reload:"restart" validate:"max=65535" desc:"Database port, 0 uses the port of storage.backend: 3306 for mysql, 5432 for postgres"`
+	User     string `yaml:"user" mapstructure:"user" default:"root" reload:"restart" validate:"required" desc:"Database user"`
+	Password string `yaml:"password" mapstructure:"password" reload:"restart" secret:"true" logger:"-" desc:"Database password, usually a secret reference (file://, env: or enc:)"`
+	Name     string `yaml:"name" mapstructure:"name" default:"app" reload:"restart" validate:"required" desc:"Database name"`
+	SSLMode  string `yaml:"ssl_mode" mapstructure:"ssl_mode" default:"disable" reload:"restart" validate:"oneof=disable allow prefer require verify-ca verify-full" desc:"SSL mode of the postgres backend"`
+}
+
+// defaultDBPorts are the ports used when db.port is 0, by storage.backend
+var defaultDBPorts = map[string]uint{
+	"mysql":    3306,
+	"postgres": 5432,
+}
+
+// setDefaultPort sets the port of the backend when none is configured, the memory backend has none
+func (db *databaseConfig) setDefaultPort(backend string) {
+	if db.Port == 0 {
+		db.Port = defaultDBPorts[backend]
+	}
 }
 
 func Database() DatabaseConfig {
-	return &cfg.DB
+	return Current().Database()
 }
 
 func (db *databaseConfig) GetHost() string {
@@ -39,3 +54,7 @@ func (db *databaseConfig) GetPassword() string {
 func (db *databaseConfig) GetName() string {
 	return db.Name
 }
+
+func (db *databaseConfig) GetSSLMode() string {
+	return db.SSLMode
+}
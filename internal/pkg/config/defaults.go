@@ -0,0 +1,90 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/go-viper/mapstructure/v2"
+	"github.com/spf13/viper"
+)
+
+// setDefaults registers the `default` tag of every key in v
+func setDefaults(v *viper.Viper) {
+	for _, f := range leafFields(reflect.TypeOf(appConfig{}), "") {
+		if def, ok := f.Struct.Tag.Lookup("default"); ok && !isStructSlice(f.Struct.Type) {
+			v.SetDefault(f.Key, defaultValue(f.Struct.Type, def))
+		}
+	}
+}
+
+// applyElementDefaults fills the defaults of struct slice elements (e.g. logger.logfile_configs),
+// viper can't do it because the number of elements is only known after reading the config.
+func applyElementDefaults(v *viper.Viper, cfg *appConfig) error {
+	root := reflect.ValueOf(cfg).Elem()
+	for _, f := range leafFields(root.Type(), "") {
+		if !isStructSlice(f.Struct.Type) {
+			continue
+		}
+
+		raw, _ := v.Get(f.Key).([]interface{})
+		slice := fieldByKey(root, f.Key)
+		for i := 0; i < slice.Len() && i < len(raw); i++ {
+			present, _ := raw[i].(map[string]interface{})
+
+			missing := map[string]interface{}{}
+			elemType := f.Struct.Type.Elem()
+			for j := 0; j < elemType.NumField(); j++ {
+				ef := elemType.Field(j)
+				def, ok := ef.Tag.Lookup("default")
+				if !ok || hasKey(present, keyName(ef)) {
+					continue
+				}
+				missing[keyName(ef)] = defaultValue(ef.Type, def)
+			}
+
+			if err := mapstructure.WeakDecode(missing, slice.Index(i).Addr().Interface()); err != nil {
+				return fmt.Errorf("%s[%d]: %w", f.Key, i, err)
+			}
+		}
+	}
+	return nil
+}
+
+func defaultValue(t reflect.Type, def string) interface{} {
+	if t.Kind() == reflect.Slice {
+		if def == "" {
+			return []string{}
+		}
+		return strings.Split(def, ",")
+	}
+	return def
+}
+
+func isStructSlice(t reflect.Type) bool {
+	return t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Struct
+}
+
+// hasKey checks a raw viper map, whose keys are case-insensitive
+func hasKey(m map[string]interface{}, key string) bool {
+	for k := range m {
+		if strings.EqualFold(k, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// fieldByKey returns the value of the struct field addressed by a dotted key
+func fieldByKey(v reflect.Value, key string) reflect.Value {
+	for _, part := range strings.Split(key, ".") {
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			if keyName(t.Field(i)) == part {
+				v = v.Field(i)
+				break
+			}
+		}
+	}
+	return v
+}
@@ -0,0 +1,39 @@
+package config
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// Environment the service runs in, set by server.env (APP_SERVER_ENV).
+// It selects the config overlay and every environment dependent behaviour, see the env package.
+type Environment string
+
+const (
+	EnvLocal       Environment = "local"
+	EnvDevelopment Environment = "development"
+	EnvStaging     Environment = "staging"
+	EnvProduction  Environment = "production"
+)
+
+// IsDevelopment reports whether dev-only behaviour (e.g. stacktraces in responses) is allowed.
+// Only an explicit development enables it, local is the default env so it must not leak debug output.
+func (e Environment) IsDevelopment() bool {
+	return e == EnvDevelopment
+}
+
+func (e Environment) IsProduction() bool {
+	return e == EnvProduction
+}
+
+// CurrentEnvironment returns the environment of the loaded config
+func CurrentEnvironment() Environment {
+	return Current().Environment()
+}
+
+// overlayPath returns the per-environment file layered on top of the base config,
+// e.g. ./config/config.yaml is overlaid by ./config/config.production.yaml
+func overlayPath(base string, env Environment) string {
+	ext := filepath.Ext(base)
+	return strings.TrimSuffix(base, ext) + "." + string(env) + ext
+}
@@ -0,0 +1,112 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// field describes a single leaf key of the configuration tree
+type field struct {
+	Key    string
+	Struct reflect.StructField
+}
+
+// keyName returns the config key of a struct field, based on its mapstructure tag
+func keyName(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("mapstructure"), ",")
+	if name == "" {
+		return strings.ToLower(f.Name)
+	}
+	return name
+}
+
+// leafFields walks t and returns every leaf key, e.g. "server.port".
+// Slices of structs are treated as leaves, their elements are handled separately.
+func leafFields(t reflect.Type, prefix string) []field {
+	var fields []field
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+
+		key := keyName(f)
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+
+		if f.Type.Kind() == reflect.Struct {
+			fields = append(fields, leafFields(f.Type, key)...)
+			continue
+		}
+		fields = append(fields, field{Key: key, Struct: f})
+	}
+	return fields
+}
+
+// envName returns the environment variable that overrides the given key.
+// Lists of structs (e.g. "logger.logfile_configs") and keys inside them can't be overridden.
+func envName(key string) string {
+	if strings.ContainsAny(key, "[]") {
+		return ""
+	}
+	for _, f := range leafFields(reflect.TypeOf(appConfig{}), "") {
+		if f.Key == key && isStructSlice(f.Struct.Type) {
+			return ""
+		}
+	}
+	return envPrefix + "_" + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+const redacted = "[Masked]"
+
+// leaf is a flattened config value along with the struct field it was read from
+type leaf struct {
+	Value  string
+	Field  reflect.StructField
+	Secret bool
+}
+
+// Display returns the value, or a placeholder when the value is a secret
+func (l leaf) Display() string {
+	if l.Secret && l.Value != "" {
+		return redacted
+	}
+	return l.Value
+}
+
+// flatten returns every leaf value of cfg keyed by its config key.
+// Struct slice elements are expanded, e.g. "logger.logfile_configs[0].levels".
+func flatten(cfg *appConfig) map[string]leaf {
+	m := map[string]leaf{}
+	flattenValue(reflect.ValueOf(cfg).Elem(), "", cfg.resolved, m)
+	return m
+}
+
+func flattenValue(v reflect.Value, prefix string, resolved map[string]string, m map[string]leaf) {
+	for _, f := range leafFields(v.Type(), prefix) {
+		fv := fieldByKey(v, strings.TrimPrefix(strings.TrimPrefix(f.Key, prefix), "."))
+		if isStructSlice(f.Struct.Type) {
+			for i := 0; i < fv.Len(); i++ {
+				flattenValue(fv.Index(i), fmt.Sprintf("%s[%d]", f.Key, i), resolved, m)
+			}
+			continue
+		}
+		m[f.Key] = leaf{
+			Value:  fmt.Sprint(fv.Interface()),
+			Field:  f.Struct,
+			Secret: isSecret(f.Struct) || resolved[f.Key] != "",
+		}
+	}
+}
+
+// isSecret reports whether the field holds a secret, which is redacted whenever the config is logged or printed.
+// Values resolved from a secret reference (see resolveSecrets) are redacted as well.
+func isSecret(f reflect.StructField) bool {
+	return f.Tag.Get("secret") == "true"
+}
+
+func isRestartOnly(f reflect.StructField) bool {
+	return f.Tag.Get("reload") == "restart"
+}
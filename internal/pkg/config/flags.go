@@ -0,0 +1,34 @@
+package config
+
+import (
+	"slices"
+
+	"starter-go/internal/pkg/flags"
+)
+
+type flagConfig struct {
+	Name      string   `yaml:"name" mapstructure:"name" validate:"required" desc:"Name the code evaluates the flag with"`
+	Enabled   bool     `yaml:"enabled" mapstructure:"enabled" default:"false" desc:"Kill switch, a disabled flag is off for everyone, including the allow-list"`
+	Rollout   uint     `yaml:"rollout" mapstructure:"rollout" default:"100" validate:"max=100" desc:"Percentage of ids the flag is on for, 100 turns it on for everyone"`
+	RolloutBy string   `yaml:"rollout_by" mapstructure:"rollout_by" default:"client_ip" validate:"oneof=client_ip user_id" desc:"Id the rollout is keyed on, the client IP or the id of the authenticated user (the verified client certificate, requires server.tls.client_auth)"`
+	Allow     []string `yaml:"allow" mapstructure:"allow" desc:"Client IPs or user ids (see rollout_by) the flag is always on for"`
+}
+
+// Flags returns the feature flags of the current config
+func Flags() []flags.Flag {
+	return Current().Flags()
+}
+
+func (cfg *appConfig) featureFlags() []flags.Flag {
+	list := make([]flags.Flag, len(cfg.Flags))
+	for i, f := range cfg.Flags {
+		list[i] = flags.Flag{
+			Name:      f.Name,
+			Enabled:   f.Enabled,
+			Rollout:   f.Rollout,
+			RolloutBy: f.RolloutBy,
+			Allow:     slices.Clone(f.Allow),
+		}
+	}
+	return list
+}
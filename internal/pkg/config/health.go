@@ -0,0 +1,29 @@
+package config
+
+type HealthConfig interface {
+	GetCheckTimeout() uint
+	GetCacheTTL() uint
+	GetMinFreeDisk() uint
+}
+
+type healthConfig struct {
+	CheckTimeout uint `yaml:"check_timeout" mapstructure:"check_timeout" default:"2000" reload:"restart" validate:"min=1" desc:"Timeout of each readiness check, in ms"`                      // in ms
+	CacheTTL     uint `yaml:"cache_ttl" mapstructure:"cache_ttl" default:"1000" reload:"restart" desc:"How long a readiness check result is reused, in ms, 0 runs the checks on every probe"` // in ms
+	MinFreeDisk  uint `yaml:"min_free_disk" mapstructure:"min_free_disk" default:"100" reload:"restart" desc:"Free space needed on the disk of the log files, in MB"`                         // in MB
+}
+
+func Health() HealthConfig {
+	return Current().Health()
+}
+
+func (h *healthConfig) GetCheckTimeout() uint {
+	return h.CheckTimeout
+}
+
+func (h *healthConfig) GetCacheTTL() uint {
+	return h.CacheTTL
+}
+
+func (h *healthConfig) GetMinFreeDisk() uint {
+	return h.MinFreeDisk
+}
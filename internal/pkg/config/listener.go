@@ -0,0 +1,46 @@
+package config
+
+import (
+	"os"
+	"strconv"
+
+	"github.com/go-playground/validator/v10"
+)
+
+type ListenerConfig interface {
+	GetNetwork() string
+	GetSocketPath() string
+	GetSocketMode() os.FileMode
+	GetUpgradeTimeout() uint
+}
+
+type listenerConfig struct {
+	Network        string `yaml:"network" mapstructure:"network" default:"tcp" reload:"restart" validate:"oneof=tcp unix systemd" desc:"tcp listens on server.port, unix on socket_path, systemd uses the socket passed by systemd socket activation (LISTEN_FDS)"`
+	SocketPath     string `yaml:"socket_path" mapstructure:"socket_path" reload:"restart" validate:"required_if=Network unix" desc:"Path of the unix socket"`
+	SocketMode     string `yaml:"socket_mode" mapstructure:"socket_mode" default:"0660" reload:"restart" validate:"file_mode" desc:"Permissions of the unix socket, in octal"`
+	UpgradeTimeout uint   `yaml:"upgrade_timeout" mapstructure:"upgrade_timeout" default:"60000" validate:"min=1" desc:"How long the new process started by SIGUSR2 has to be ready before it's killed and the current one keeps serving, in ms"` // in ms
+}
+
+func (l *listenerConfig) GetNetwork() string {
+	return l.Network
+}
+
+func (l *listenerConfig) GetSocketPath() string {
+	return l.SocketPath
+}
+
+// GetSocketMode returns the permissions of socket_mode
+func (l *listenerConfig) GetSocketMode() os.FileMode {
+	mode, _ := strconv.ParseUint(l.SocketMode, 8, 32)
+	return os.FileMode(mode)
+}
+
+func (l *listenerConfig) GetUpgradeTimeout() uint {
+	return l.UpgradeTimeout
+}
+
+// isFileMode checks the value is octal permissions, e.g. 0660
+func isFileMode(fl validator.FieldLevel) bool {
+	mode, err := strconv.ParseUint(fl.Field().String(), 8, 32)
+	return err == nil && mode <= 0o777
+}
@@ -0,0 +1,235 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"reflect"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// Loader reads the configuration from files and env vars.
+// Loaders don't share any state, so several configurations can be loaded side by side (e.g. in parallel tests).
+type Loader struct {
+	path      string
+	lookupEnv func(string) (string, bool)
+	flags     *pflag.FlagSet
+	remote    *remoteSource
+	offline   bool
+}
+
+type LoaderOption func(*Loader)
+
+// WithLookupEnv replaces os.LookupEnv, for APP_ overrides and env: secret references
+func WithLookupEnv(lookupEnv func(string) (string, bool)) LoaderOption {
+	return func(l *Loader) {
+		l.lookupEnv = lookupEnv
+	}
+}
+
+// WithOffline skips what depends on the machine loading the config, to validate config files before deploying them:
+// the remote document isn't fetched, and secret references are checked but not resolved
+func WithOffline() LoaderOption {
+	return func(l *Loader) {
+		l.offline = true
+	}
+}
+
+// NewLoader creates a loader for the base config file at path
+func NewLoader(path string, opts ...LoaderOption) *Loader {
+	l := &Loader{
+		path:      path,
+		lookupEnv: os.LookupEnv,
+		remote:    &remoteSource{},
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// Load reads, validates and returns a new configuration.
+// Config is layered, from the lowest to the highest precedence:
+// defaults, the base file, the overlay of the environment (e.g. config.production.yaml),
+// the remote document (remote.url), APP_ env vars and flags (see WithFlags).
+// When validation fails, the returned *ValidationError lists every invalid or unknown key, with its line when read from a file.
+func (l *Loader) Load() (*Config, error) {
+	c, remote, err := l.load()
+	if err != nil {
+		return nil, err
+	}
+	l.remote.commit(remote)
+	return &Config{c: c}, nil
+}
+
+// LoadServer reads the server section from the files, env vars and flags, without the remote document, secrets or validation.
+// It's for the commands only needing the listen address, e.g. healthcheck run every few seconds.
+func (l *Loader) LoadServer() (ServerConfig, error) {
+	v, _, _, err := l.read()
+	if err != nil {
+		return nil, err
+	}
+
+	loaded := new(appConfig)
+	if err := v.Unmarshal(loaded); err != nil {
+		return nil, fmt.Errorf("error reading config, %s", err)
+	}
+	return &loaded.Server, nil
+}
+
+// load uses a fresh viper instance every time, so keys removed from the files don't linger across reloads.
+// It returns the remote document the config was built from, nil without remote.url,
+// the caller commits it once the config is accepted so a rejected document is fetched again.
+func (l *Loader) load() (_ *appConfig, _ *remoteDocument, err error) {
+	v, files, env, err := l.read()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var remote *remoteDocument
+	if !l.offline {
+		if remote, err = l.mergeRemote(v, env); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// decoding keeps going after a bad value, so unknown keys, decode and validation errors are reported together
+	loaded := new(appConfig)
+	verr := &ValidationError{}
+
+	indexes := map[string]*keyIndex{}
+	for _, path := range files {
+		idx, err := indexFile(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		indexes[path] = idx
+		for _, key := range idx.unknown {
+			verr.Errors = append(verr.Errors, FieldError{Key: key, Message: "unknown key", File: path, Line: idx.lines[key]})
+		}
+	}
+	if remote != nil {
+		idx, err := indexDocument(remote.URL, remote.Body)
+		if err != nil {
+			return nil, nil, err
+		}
+		indexes[remote.URL] = idx
+		for _, key := range idx.unknown {
+			verr.Errors = append(verr.Errors, FieldError{Key: key, Message: "unknown key", File: remote.URL, Line: idx.lines[key]})
+		}
+	}
+
+	if err := v.Unmarshal(loaded); err != nil {
+		collectDecodeErrors(err, verr)
+	}
+
+	if err := applyElementDefaults(v, loaded); err != nil {
+		return nil, nil, fmt.Errorf("error setting config defaults, %s", err)
+	}
+	// the default port depends on the backend, only known once decoded
+	loaded.DB.setDefaultPort(loaded.Storage.Backend)
+
+	resolveSecrets(loaded, l.lookupEnv, !l.offline, verr)
+	loaded.files = files
+	loaded.sources, err = l.sources(files, remote)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := validateConfig(loaded, verr); err != nil {
+		verr.locate(loaded.sources, indexes)
+		return nil, nil, err
+	}
+
+	loaded.derive()
+	return loaded, remote, nil
+}
+
+// read layers the defaults, the base file, env vars, flags and the overlay of the environment, it returns the files read
+func (l *Loader) read() (*viper.Viper, []string, Environment, error) {
+	v := viper.New()
+	v.SetConfigFile(l.path)
+	v.SetConfigType("yaml")
+
+	setDefaults(v)
+
+	if err := v.ReadInConfig(); err != nil {
+		return nil, nil, "", fmt.Errorf("error reading config file, %s", err)
+	}
+	files := []string{l.path}
+
+	// env vars and flags are applied as overrides, so they take precedence over the overlay read below
+	l.applyEnv(v)
+	l.applyFlags(v)
+
+	// the environment is known once the base file and env vars are read, its overlay is optional
+	env := Environment(v.GetString("server.env"))
+	overlay := overlayPath(l.path, env)
+	if _, err := os.Stat(overlay); err == nil {
+		v.SetConfigFile(overlay)
+		if err := v.MergeInConfig(); err != nil {
+			return nil, nil, "", fmt.Errorf("error reading config overlay, %s", err)
+		}
+		if overridden := Environment(v.GetString("server.env")); overridden != env {
+			return nil, nil, "", fmt.Errorf("config overlay %s can't change server.env to %q", overlay, overridden)
+		}
+		files = append(files, overlay)
+	}
+	return v, files, env, nil
+}
+
+// mergeRemote layers the remote document on top of the files when remote.url is set, it returns nil otherwise
+func (l *Loader) mergeRemote(v *viper.Viper, env Environment) (*remoteDocument, error) {
+	conf, secret, err := remoteSettings(v, l.lookupEnv)
+	if err != nil {
+		return nil, err
+	}
+	if conf = l.remote.pin(conf, secret); conf.URL == "" {
+		return nil, nil
+	}
+
+	remote, err := l.remote.load()
+	if err != nil {
+		return nil, err
+	}
+
+	layer := viper.New()
+	layer.SetConfigType("yaml")
+	if err := layer.ReadConfig(bytes.NewReader(remote.Body)); err != nil {
+		return nil, fmt.Errorf("error parsing remote config %s, %s", conf.URL, err)
+	}
+	// like overlays, the remote document can't change what selected it
+	if layer.IsSet("remote") {
+		return nil, fmt.Errorf("remote config %s can't change the remote section", conf.URL)
+	}
+	if layer.IsSet("server.env") && Environment(layer.GetString("server.env")) != env {
+		return nil, fmt.Errorf("remote config %s can't change server.env to %q", conf.URL, layer.GetString("server.env"))
+	}
+
+	if err := v.MergeConfigMap(layer.AllSettings()); err != nil {
+		return nil, fmt.Errorf("error merging remote config %s, %s", conf.URL, err)
+	}
+	return remote, nil
+}
+
+// derive sets the values computed from the loaded keys, it must be called again when keys are changed
+func (cfg *appConfig) derive() {
+	// time_zone is validated, it can't fail here
+	cfg.Server.location, _ = time.LoadLocation(cfg.Server.TimeZone)
+}
+
+// applyEnv overrides every key set by its env var, a.b.c is APP_A_B_C.
+// Empty env vars are ignored. Lists are comma separated, e.g. APP_CORS_ALLOW_ORIGINS=https://a.com,https://b.com
+func (l *Loader) applyEnv(v *viper.Viper) {
+	for _, f := range leafFields(reflect.TypeOf(appConfig{}), "") {
+		if isStructSlice(f.Struct.Type) {
+			continue
+		}
+		if value, ok := l.lookupEnv(envName(f.Key)); ok && value != "" {
+			v.Set(f.Key, value)
+		}
+	}
+}
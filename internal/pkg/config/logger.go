@@ -1,33 +1,40 @@
 package config
 
 import (
+	"slices"
+
 	"starter-go/internal/pkg/logger"
 )
 
 type loggerConfig struct {
-	EnableStdout   bool            `yaml:"enable_stdout" mapstructure:"enable_stdout"`
-	EnableLogFile  bool            `yaml:"enable_logfile" mapstructure:"enable_logfile"`
-	CallerSkipSet  bool            `yaml:"caller_skipset" mapstructure:"caller_skipset"`
-	CallerSkip     int             `yaml:"caller_skip" mapstructure:"caller_skip"`
-	LogFileConfigs []logFileConfig `yaml:"logfile_configs" mapstructure:"logfile_configs"`
+	EnableStdout   bool            `yaml:"enable_stdout" mapstructure:"enable_stdout" default:"true" desc:"Write logs to stdout"`
+	EnableLogFile  bool            `yaml:"enable_logfile" mapstructure:"enable_logfile" default:"false" desc:"Write logs to the files of logfile_configs"`
+	CallerSkipSet  bool            `yaml:"caller_skipset" mapstructure:"caller_skipset" default:"true" desc:"Skip the logger wrapper frames when reporting the caller"`
+	CallerSkip     int             `yaml:"caller_skip" mapstructure:"caller_skip" default:"2" validate:"min=0" desc:"Number of frames skipped when reporting the caller"`
+	LogFileConfigs []logFileConfig `yaml:"logfile_configs" mapstructure:"logfile_configs" validate:"dive" desc:"Log files, each one receives the given levels"`
 }
 
 type logFileConfig struct {
-	Levels           []string `yaml:"levels" mapstructure:"levels"`
-	IsAccessLog      bool     `yaml:"is_access_log" mapstructure:"is_access_log"`
-	FullpathFilename string   `yaml:"fullpath_filename" mapstructure:"fullpath_filename"`
-	MaxSize          int      `yaml:"max_size" mapstructure:"max_size"`
-	MaxAge           int      `yaml:"max_age" mapstructure:"max_age"`
-	MaxBackups       int      `yaml:"max_backups" mapstructure:"max_backups"`
-	LocalTime        bool     `yaml:"local_time" mapstructure:"local_time"`
-	Compress         bool     `yaml:"compress" mapstructure:"compress"`
+	Levels           []string `yaml:"levels" mapstructure:"levels" validate:"min=1,dive,oneof=debug info warn error dpanic panic fatal" desc:"Levels written to this file"`
+	IsAccessLog      bool     `yaml:"is_access_log" mapstructure:"is_access_log" default:"false" desc:"Write the HTTP access logs to this file"`
+	FullpathFilename string   `yaml:"fullpath_filename" mapstructure:"fullpath_filename" validate:"logfile_path" desc:"Path of the log file, missing directories are created"`
+	MaxSize          int      `yaml:"max_size" mapstructure:"max_size" default:"500" validate:"min=0" desc:"Maximum size before the file is rotated, in MB"` // in MB
+	MaxAge           int      `yaml:"max_age" mapstructure:"max_age" default:"7" validate:"min=0" desc:"Maximum age of rotated files, in days"`              // in days
+	MaxBackups       int      `yaml:"max_backups" mapstructure:"max_backups" default:"0" validate:"min=0" desc:"Maximum number of rotated files kept, 0 keeps all of them"`
+	LocalTime        bool     `yaml:"local_time" mapstructure:"local_time" default:"true" desc:"Use the local time in the names of rotated files"`
+	Compress         bool     `yaml:"compress" mapstructure:"compress" default:"false" desc:"Gzip rotated files"`
 }
 
+// LoggerConfig returns the logger section of the current config, along with the level from server.loglevel
 func LoggerConfig() logger.LogConfig {
+	return Current().Logger()
+}
+
+func (cfg *appConfig) logConfig() logger.LogConfig {
 	var logFileConfigs []logger.LogFileConfig
 	for _, fileConf := range cfg.Logger.LogFileConfigs {
 		logFileConfigs = append(logFileConfigs, logger.LogFileConfig{
-			Levels:           fileConf.Levels,
+			Levels:           slices.Clone(fileConf.Levels),
 			IsAccessLog:      fileConf.IsAccessLog,
 			FullpathFilename: fileConf.FullpathFilename,
 			MaxSize:          fileConf.MaxSize,
@@ -38,7 +45,12 @@ func LoggerConfig() logger.LogConfig {
 		})
 	}
 
+	// validated on load, unknown levels can't get here
+	level, _ := logger.ParseLevel(cfg.Server.Loglevel)
+
 	return logger.LogConfig{
+		Level:          level,
+		Location:       cfg.Server.location,
 		EnableStdout:   cfg.Logger.EnableStdout,
 		EnableLogFile:  cfg.Logger.EnableLogFile,
 		CallerSkipSet:  cfg.Logger.CallerSkipSet,
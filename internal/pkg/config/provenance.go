@@ -0,0 +1,176 @@
+package config
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+// Kinds of Source, listed from the lowest to the highest precedence
+const (
+	SourceDefault = "default"
+	SourceFile    = "file"
+	SourceRemote  = "remote"
+	SourceEnv     = "env"
+	SourceFlag    = "flag"
+)
+
+// Source tells where the value of a config key was read from
+type Source struct {
+	Kind string `json:"kind" yaml:"kind"`
+	// Name is the config file path, the remote URL, the env var or the flag, empty for defaults
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+	// Ref is the secret reference the value was resolved from, e.g. env:DB_PASSWORD
+	Ref string `json:"ref,omitempty" yaml:"ref,omitempty"`
+}
+
+func (s Source) String() string {
+	str := s.Kind
+	if s.Name != "" {
+		str += ":" + s.Name
+	}
+	if s.Ref != "" {
+		str += " (" + s.Ref + ")"
+	}
+	return str
+}
+
+// sources finds where every key comes from, following the loader's precedence: flag > env > remote > files (last one wins) > default
+func (l *Loader) sources(files []string, remote *remoteDocument) (map[string]Source, error) {
+	layers := make([]*viper.Viper, len(files))
+	for i, path := range files {
+		layers[i] = viper.New()
+		layers[i].SetConfigFile(path)
+		layers[i].SetConfigType("yaml")
+		if err := layers[i].ReadInConfig(); err != nil {
+			return nil, fmt.Errorf("error reading config file, %s", err)
+		}
+	}
+
+	var remoteLayer *viper.Viper
+	if remote != nil {
+		remoteLayer = viper.New()
+		remoteLayer.SetConfigType("yaml")
+		if err := remoteLayer.ReadConfig(bytes.NewReader(remote.Body)); err != nil {
+			return nil, fmt.Errorf("error parsing remote config %s, %s", remote.URL, err)
+		}
+	}
+
+	m := map[string]Source{}
+	for _, f := range leafFields(reflect.TypeOf(appConfig{}), "") {
+		m[f.Key] = Source{Kind: SourceDefault}
+
+		if l.flagSet(f.Key) && !isStructSlice(f.Struct.Type) {
+			m[f.Key] = Source{Kind: SourceFlag, Name: "--" + f.Key}
+			continue
+		}
+		if value, ok := l.lookupEnv(envName(f.Key)); ok && value != "" && !isStructSlice(f.Struct.Type) {
+			m[f.Key] = Source{Kind: SourceEnv, Name: envName(f.Key)}
+			continue
+		}
+		if remoteLayer != nil && remoteLayer.InConfig(f.Key) {
+			m[f.Key] = Source{Kind: SourceRemote, Name: remote.URL}
+			continue
+		}
+		for i := len(layers) - 1; i >= 0; i-- {
+			if layers[i].InConfig(f.Key) {
+				m[f.Key] = Source{Kind: SourceFile, Name: files[i]}
+				break
+			}
+		}
+	}
+	return m, nil
+}
+
+// Effective is the loaded configuration, with secrets redacted, and the source of every key
+type Effective struct {
+	Config  map[string]interface{} `json:"config" yaml:"config"`
+	Sources map[string]string      `json:"sources" yaml:"sources"`
+}
+
+// EffectiveConfig returns the configuration the service is running with
+func EffectiveConfig() Effective {
+	return Current().Effective()
+}
+
+// Effective returns cfg with secrets redacted, and the source of every key
+func (c *Config) Effective() Effective {
+	cfg := c.c
+
+	srcs := map[string]string{}
+	for key, src := range cfg.sources {
+		src.Ref = cfg.resolved[key]
+		srcs[key] = src.String()
+	}
+
+	return Effective{
+		Config:  effectiveMap(reflect.ValueOf(cfg).Elem(), "", cfg.resolved),
+		Sources: srcs,
+	}
+}
+
+// Hash returns the hex encoded SHA-256 of the effective config, to tell whether instances run the same config.
+// Secrets are hashed redacted, so it doesn't change when only a secret does.
+func (c *Config) Hash() string {
+	// map keys are sorted, so equal configs always encode the same
+	b, _ := json.Marshal(effectiveMap(reflect.ValueOf(c.c).Elem(), "", c.c.resolved))
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// Marshal encodes e as "yaml" or "json"
+func (e Effective) Marshal(format string) ([]byte, error) {
+	switch format {
+	case "yaml":
+		var buf bytes.Buffer
+		enc := yaml.NewEncoder(&buf)
+		enc.SetIndent(2)
+		if err := enc.Encode(e); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	case "json":
+		return json.MarshalIndent(e, "", "  ")
+	default:
+		return nil, fmt.Errorf("unsupported format %q, must be yaml or json", format)
+	}
+}
+
+// effectiveMap converts a config struct to a map keyed by config keys, redacting secrets
+func effectiveMap(v reflect.Value, prefix string, resolved map[string]string) map[string]interface{} {
+	m := map[string]interface{}{}
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+
+		name := keyName(f)
+		key := strings.TrimPrefix(prefix+"."+name, ".")
+		fv := v.Field(i)
+
+		switch {
+		case f.Type.Kind() == reflect.Struct:
+			m[name] = effectiveMap(fv, key, resolved)
+		case isStructSlice(f.Type):
+			items := make([]interface{}, fv.Len())
+			for j := 0; j < fv.Len(); j++ {
+				items[j] = effectiveMap(fv.Index(j), fmt.Sprintf("%s[%d]", key, j), resolved)
+			}
+			m[name] = items
+		case (isSecret(f) || resolved[key] != "") && !fv.IsZero():
+			m[name] = redacted
+		default:
+			m[name] = fv.Interface()
+		}
+	}
+	return m
+}
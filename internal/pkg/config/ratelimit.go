@@ -0,0 +1,81 @@
+package config
+
+import (
+	"slices"
+	"time"
+
+	"starter-go/internal/pkg/ratelimit"
+)
+
+type RateLimitConfig interface {
+	GetEnabled() bool
+	GetAPIKeyHeader() string
+	GetMaxKeys() uint
+	GetAPIKeys() ratelimit.APIKeys
+	GetPolicies() []ratelimit.Policy
+}
+
+type rateLimitAPIKeyConfig struct {
+	Name string `yaml:"name" mapstructure:"name" validate:"required" desc:"Client of the API key, its requests share a bucket"`
+	Key  string `yaml:"key" mapstructure:"key" secret:"true" logger:"-" validate:"required" desc:"API key sent in the api_key_header header, usually a secret reference (file://, env: or enc:)"`
+}
+
+type rateLimitPolicyConfig struct {
+	Path    string   `yaml:"path" mapstructure:"path" validate:"required,startswith=/" desc:"Route group the policy applies to, a path prefix of the routes, e.g. /api/v1/examples"`
+	Methods []string `yaml:"methods" mapstructure:"methods" validate:"dive,oneof=GET HEAD POST PUT PATCH DELETE OPTIONS" desc:"Methods limited, empty limits every method"`
+	Limit   uint     `yaml:"limit" mapstructure:"limit" validate:"min=1" desc:"Requests allowed per period"`
+	Period  uint     `yaml:"period" mapstructure:"period" default:"60000" validate:"min=1" desc:"Period of limit, in ms"` // in ms
+	Burst   uint     `yaml:"burst" mapstructure:"burst" desc:"Requests allowed at once, the size of the token bucket, 0 is limit"`
+	KeyBy   string   `yaml:"key_by" mapstructure:"key_by" default:"ip" validate:"oneof=ip api_key subject" desc:"What the requests are counted by: ip, api_key (the client of the api_key_header header, one of api_keys) or subject (the authenticated client), falling back to ip"`
+}
+
+type rateLimitConfig struct {
+	Enabled      bool                    `yaml:"enabled" mapstructure:"enabled" default:"true" desc:"Limit the requests of the route groups of policies"`
+	APIKeyHeader string                  `yaml:"api_key_header" mapstructure:"api_key_header" default:"X-API-Key" validate:"required" desc:"Header of the API key, for the policies keyed by api_key"`
+	MaxKeys      uint                    `yaml:"max_keys" mapstructure:"max_keys" default:"100000" reload:"restart" validate:"min=1" desc:"Maximum number of clients tracked in memory, the buckets full again are evicted, new clients are limited while every bucket is refilling"`
+	APIKeys      []rateLimitAPIKeyConfig `yaml:"api_keys" mapstructure:"api_keys" validate:"unique=Name,dive" desc:"API keys of the clients counted by api_key, required by the policies keyed by api_key"`
+	Policies     []rateLimitPolicyConfig `yaml:"policies" mapstructure:"policies" validate:"dive" desc:"Rate limits of route groups, the longest matching path applies, see README"`
+}
+
+func RateLimit() RateLimitConfig {
+	return Current().RateLimit()
+}
+
+func (r *rateLimitConfig) GetEnabled() bool {
+	return r.Enabled
+}
+
+func (r *rateLimitConfig) GetAPIKeyHeader() string {
+	return r.APIKeyHeader
+}
+
+func (r *rateLimitConfig) GetMaxKeys() uint {
+	return r.MaxKeys
+}
+
+func (r *rateLimitConfig) GetAPIKeys() ratelimit.APIKeys {
+	keys := make(ratelimit.APIKeys, len(r.APIKeys))
+	for i, k := range r.APIKeys {
+		keys[i] = ratelimit.APIKey{Name: k.Name, Key: k.Key}
+	}
+	return keys
+}
+
+// GetPolicies returns the policies, none when rate limiting is disabled
+func (r *rateLimitConfig) GetPolicies() []ratelimit.Policy {
+	if !r.Enabled {
+		return nil
+	}
+	policies := make([]ratelimit.Policy, len(r.Policies))
+	for i, p := range r.Policies {
+		policies[i] = ratelimit.Policy{
+			Path:    p.Path,
+			Methods: slices.Clone(p.Methods),
+			Limit:   p.Limit,
+			Period:  time.Duration(p.Period) * time.Millisecond,
+			Burst:   p.Burst,
+			KeyBy:   p.KeyBy,
+		}
+	}
+	return policies
+}
@@ -0,0 +1,240 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/viper"
+
+	"starter-go/internal/pkg/flags"
+	"starter-go/internal/pkg/logger"
+)
+
+type Section string
+
+// Sections that can be subscribed to, a section is notified when any of its keys changed on reload
+const (
+	SectionServer    Section = "server"
+	SectionLogger    Section = "logger"
+	SectionDB        Section = "db"
+	SectionStorage   Section = "storage"
+	SectionCORS      Section = "cors"
+	SectionHealth    Section = "health"
+	SectionScheduler Section = "scheduler"
+	SectionAdmin     Section = "admin"
+	SectionTimeout   Section = "timeout"
+	SectionRateLimit Section = "ratelimit"
+	SectionFlags     Section = "flags"
+)
+
+// sectionKeys lists the key prefixes of each section,
+// the logger section also covers server.loglevel since it sets the logger threshold
+var sectionKeys = map[Section][]string{
+	SectionServer:    {"server."},
+	SectionLogger:    {"logger.", "server.loglevel"},
+	SectionDB:        {"db."},
+	SectionStorage:   {"storage."},
+	SectionCORS:      {"cors."},
+	SectionHealth:    {"health."},
+	SectionScheduler: {"scheduler."},
+	SectionAdmin:     {"admin."},
+	SectionTimeout:   {"timeout."},
+	SectionRateLimit: {"ratelimit."},
+	SectionFlags:     {"flags["},
+}
+
+var (
+	reloadMu    sync.Mutex
+	subsMu      sync.Mutex
+	subscribers = map[Section][]func(){}
+)
+
+// Subscribe registers fn to be called after a reload changed any key of section.
+// fn reads the new values through the usual accessors, e.g. config.Server().
+func Subscribe(section Section, fn func()) {
+	subsMu.Lock()
+	defer subsMu.Unlock()
+	subscribers[section] = append(subscribers[section], fn)
+}
+
+// OnServerChange registers fn to be called with the new server config after a reload changed it
+func OnServerChange(fn func(ServerConfig)) {
+	Subscribe(SectionServer, func() { fn(Server()) })
+}
+
+// OnLoggerChange registers fn to be called with the new logger config after a reload changed the level or the sinks
+func OnLoggerChange(fn func(logger.LogConfig)) {
+	Subscribe(SectionLogger, func() { fn(LoggerConfig()) })
+}
+
+// OnCORSChange registers fn to be called with the new CORS config after a reload changed it
+func OnCORSChange(fn func(CORSConfig)) {
+	Subscribe(SectionCORS, func() { fn(CORS()) })
+}
+
+// OnTimeoutChange registers fn to be called with the new timeouts after a reload changed them
+func OnTimeoutChange(fn func(TimeoutConfig)) {
+	Subscribe(SectionTimeout, func() { fn(Timeout()) })
+}
+
+// OnRateLimitChange registers fn to be called with the new rate limits after a reload changed them
+func OnRateLimitChange(fn func(RateLimitConfig)) {
+	Subscribe(SectionRateLimit, func() { fn(RateLimit()) })
+}
+
+// OnFlagsChange registers fn to be called with the new feature flags after a reload changed any of them
+func OnFlagsChange(fn func([]flags.Flag)) {
+	Subscribe(SectionFlags, func() { fn(Flags()) })
+}
+
+// Watch reloads the config whenever one of the config files read by Init (base and overlay) is written,
+// or the remote document changed (polled every remote.poll_interval).
+// Kubernetes ConfigMap updates (symlink swaps) are handled by viper.
+func Watch() {
+	for _, path := range get().files {
+		v := viper.New()
+		v.SetConfigFile(path)
+		v.SetConfigType("yaml")
+		v.OnConfigChange(func(e fsnotify.Event) {
+			_ = Reload()
+		})
+		v.WatchConfig()
+	}
+
+	if defaultLoader == nil {
+		return
+	}
+	if interval := defaultLoader.remote.pollInterval(); interval > 0 {
+		go pollRemote(defaultLoader.remote, interval)
+	}
+}
+
+// pollRemote reloads the config when the remote document changed, an unreachable URL keeps the current config
+func pollRemote(remote *remoteSource, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		changed, err := remote.poll()
+		if err != nil {
+			logger.Warn("[Config] unable to poll remote config", "error", err.Error())
+			continue
+		}
+		if changed {
+			_ = Reload()
+		}
+	}
+}
+
+// Reload re-reads the config file passed to Init.
+// The new config is validated and swapped in as a whole, an invalid config is rejected and the current one is kept.
+// Changes to keys tagged `reload:"restart"` (e.g. server.port) are not applied until the next restart,
+// the new config is validated again with their running values.
+func Reload() error {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	if defaultLoader == nil {
+		return errors.New("config is not initialized, Init must be called before Reload")
+	}
+
+	old := get()
+	loaded, remote, err := defaultLoader.load()
+	if err != nil {
+		logger.Error("[Config] reload rejected, keeping current config", "error", err.Error())
+		return err
+	}
+
+	oldValues, newValues := flatten(old), flatten(loaded)
+
+	var changes, ignored []string
+	for _, key := range changedKeys(oldValues, newValues) {
+		before, after := oldValues[key], newValues[key]
+		if isRestartOnly(after.Field) {
+			// keep the running value, the new one is picked up on restart
+			fieldByKey(reflect.ValueOf(loaded).Elem(), key).Set(fieldByKey(reflect.ValueOf(old).Elem(), key))
+			loaded.resolved[key] = old.resolved[key]
+			loaded.sources[key] = old.sources[key]
+			ignored = append(ignored, key)
+			continue
+		}
+		changes = append(changes, fmt.Sprintf("%s: %q -> %q", key, before.Display(), after.Display()))
+	}
+
+	// the running values of the restart-only keys must fit the new ones, e.g. shutdown_grace below shutdown_timeout
+	if err := validateConfig(loaded, &ValidationError{}); err != nil {
+		logger.Error("[Config] reload rejected, the changes conflict with the restart-only keys kept until restart, keeping current config", "error", err.Error())
+		return err
+	}
+
+	loaded.derive()
+
+	if len(ignored) > 0 {
+		logger.Warn("[Config] ignored changes to restart-only keys", "keys", ignored)
+	}
+	if len(changes) == 0 {
+		defaultLoader.remote.commit(remote)
+		logger.Info("[Config] reloaded, nothing to apply")
+		return nil
+	}
+
+	current.Store(&Config{c: loaded})
+	// committed once accepted, a rejected remote document keeps being polled as changed
+	defaultLoader.remote.commit(remote)
+	logger.Info("[Config] reloaded", "changes", changes)
+
+	notify(changedSections(changes))
+	return nil
+}
+
+// changedKeys returns the sorted keys whose value differ, including keys only present on one side
+func changedKeys(old, new map[string]leaf) []string {
+	var keys []string
+	for key, l := range new {
+		if o, ok := old[key]; !ok || o.Value != l.Value {
+			keys = append(keys, key)
+		}
+	}
+	for key, l := range old {
+		if _, ok := new[key]; !ok {
+			// removed slice element, report it with the new side's field so restart-only checks still work
+			new[key] = leaf{Field: l.Field}
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func changedSections(changes []string) map[Section]bool {
+	sections := map[Section]bool{}
+	for _, change := range changes {
+		for section, prefixes := range sectionKeys {
+			for _, prefix := range prefixes {
+				if strings.HasPrefix(change, prefix) {
+					sections[section] = true
+				}
+			}
+		}
+	}
+	return sections
+}
+
+func notify(sections map[Section]bool) {
+	subsMu.Lock()
+	var fns []func()
+	for section := range sections {
+		fns = append(fns, subscribers[section]...)
+	}
+	subsMu.Unlock()
+
+	for _, fn := range fns {
+		fn()
+	}
+}
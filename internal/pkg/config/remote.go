@@ -0,0 +1,279 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-viper/mapstructure/v2"
+	"github.com/spf13/viper"
+
+	"starter-go/internal/pkg/logger"
+)
+
+// signatureHeader holds the hex encoded HMAC-SHA256 of the remote document, keyed by remote.secret
+const signatureHeader = "X-Config-Signature"
+
+// maxRemoteSize is the largest remote document read, a config is a few KB
+const maxRemoteSize = 1 << 20
+
+type remoteConfig struct {
+	URL          string `yaml:"url" mapstructure:"url" reload:"restart" validate:"omitempty,url" desc:"URL of a config document (JSON or YAML) layered on top of the config files, empty disables it"`
+	Secret       string `yaml:"secret" mapstructure:"secret" reload:"restart" secret:"true" logger:"-" validate:"required_with=URL" desc:"Shared secret of the HMAC-SHA256 signature of the document, sent in the X-Config-Signature header"`
+	PollInterval uint   `yaml:"poll_interval" mapstructure:"poll_interval" default:"30000" reload:"restart" validate:"min=1000" desc:"Interval between two checks of the document for changes, in ms"`
+	Timeout      uint   `yaml:"timeout" mapstructure:"timeout" default:"5000" reload:"restart" validate:"min=1" desc:"Timeout of a request to the document, in ms"`
+	CachePath    string `yaml:"cache_path" mapstructure:"cache_path" reload:"restart" desc:"File the last verified document is cached in, used when the URL is unreachable on startup, empty disables it"`
+}
+
+// remoteDocument is a verified config document
+type remoteDocument struct {
+	URL       string `json:"url"`
+	ETag      string `json:"etag"`
+	Signature string `json:"signature"`
+	Body      []byte `json:"body"`
+}
+
+// remoteSource fetches the remote config document, it keeps the last applied document
+// so unchanged documents aren't downloaded again (If-None-Match) and an unreachable URL doesn't fail a reload
+type remoteSource struct {
+	mu sync.Mutex
+	// the remote section is restart-only, the settings of the first load are kept
+	pinned bool
+	conf   remoteConfig
+	secret string
+	// last is the document of the current config, set by commit once the config built from it is valid
+	last *remoteDocument
+	// pending is the changed document found by poll, used by the reload it triggers instead of downloading it again
+	pending *remoteDocument
+}
+
+// remoteSettings reads the remote section before the config is unmarshalled, since it's needed to build the config
+func remoteSettings(v *viper.Viper, lookupEnv func(string) (string, bool)) (remoteConfig, string, error) {
+	// keys are read one by one, viper doesn't merge defaults into a section returned as a whole
+	var conf remoteConfig
+	raw := map[string]interface{}{}
+	for _, f := range leafFields(reflect.TypeOf(conf), "remote") {
+		raw[keyName(f.Struct)] = v.Get(f.Key)
+	}
+	if err := mapstructure.WeakDecode(raw, &conf); err != nil {
+		return conf, "", fmt.Errorf("error reading remote config settings, %s", err)
+	}
+	if conf.URL == "" {
+		return conf, "", nil
+	}
+
+	secret := conf.Secret
+	if isRef(secret) {
+		var err error
+		if secret, err = resolveRef(secret, lookupEnv); err != nil {
+			return conf, "", fmt.Errorf("remote.secret: %s", err)
+		}
+	}
+	if secret == "" {
+		return conf, "", errors.New("remote.secret is required to verify the remote config")
+	}
+	return conf, secret, nil
+}
+
+// pin keeps the settings of the first load and returns them
+func (r *remoteSource) pin(conf remoteConfig, secret string) remoteConfig {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.pinned {
+		r.pinned, r.conf, r.secret = true, conf, secret
+	}
+	return r.conf
+}
+
+// load returns the remote document, the last applied one (in memory, then on disk) is used when it can't be fetched
+func (r *remoteSource) load() (*remoteDocument, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if doc := r.pending; doc != nil {
+		r.pending = nil
+		return doc, nil
+	}
+
+	conf := r.conf
+	doc, _, err := r.fetch()
+	if err == nil {
+		return doc, nil
+	}
+
+	if r.last == nil && conf.CachePath != "" {
+		if cached, cacheErr := readCache(conf.CachePath, conf.URL, r.secret); cacheErr == nil {
+			r.last = cached
+		}
+	}
+	if r.last == nil {
+		return nil, fmt.Errorf("error reading remote config, %s", err)
+	}
+
+	logger.Warn("[Config] remote config unavailable, using the last verified document", "url", conf.URL, "error", err.Error())
+	return r.last, nil
+}
+
+// poll checks whether the document differs from the last applied one.
+// A rejected document keeps being reported as changed, so the config is reloaded once the document is fixed.
+func (r *remoteSource) poll() (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	doc, changed, err := r.fetch()
+	r.pending = nil
+	if changed {
+		r.pending = doc
+	}
+	return changed, err
+}
+
+func (r *remoteSource) pollInterval() time.Duration {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.conf.URL == "" {
+		return 0
+	}
+	return time.Duration(r.conf.PollInterval) * time.Millisecond
+}
+
+// fetch downloads the document unless it matches the ETag of the last applied one, r.mu must be held
+func (r *remoteSource) fetch() (*remoteDocument, bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(r.conf.Timeout)*time.Millisecond)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.conf.URL, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	req.Header.Set("Accept", "application/json, application/yaml")
+	if r.last != nil && r.last.ETag != "" {
+		req.Header.Set("If-None-Match", r.last.ETag)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, false, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusNotModified:
+		if r.last == nil {
+			return nil, false, errors.New("remote config not modified, but no document was fetched before")
+		}
+		return r.last, false, nil
+	case http.StatusOK:
+	default:
+		return nil, false, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxRemoteSize+1))
+	if err != nil {
+		return nil, false, err
+	}
+	if len(body) > maxRemoteSize {
+		return nil, false, fmt.Errorf("remote config is larger than %d bytes", maxRemoteSize)
+	}
+
+	doc := &remoteDocument{
+		URL:       r.conf.URL,
+		ETag:      resp.Header.Get("ETag"),
+		Signature: resp.Header.Get(signatureHeader),
+		Body:      body,
+	}
+	if err := doc.verify(r.secret); err != nil {
+		return nil, false, err
+	}
+
+	changed := r.last == nil || !bytes.Equal(r.last.Body, doc.Body)
+	return doc, changed, nil
+}
+
+// commit keeps doc as the last applied document and caches it on disk,
+// it's only called once the config built from doc is accepted. A nil doc is ignored.
+func (r *remoteSource) commit(doc *remoteDocument) {
+	if doc == nil {
+		return
+	}
+
+	r.mu.Lock()
+	r.last = doc
+	path := r.conf.CachePath
+	r.mu.Unlock()
+
+	if path == "" {
+		return
+	}
+	if err := writeCache(path, doc); err != nil {
+		logger.Warn("[Config] unable to cache remote config", "path", path, "error", err.Error())
+	}
+}
+
+// verify checks the HMAC-SHA256 signature of the document
+func (d *remoteDocument) verify(secret string) error {
+	signature, err := hex.DecodeString(strings.TrimPrefix(d.Signature, "sha256="))
+	if err != nil || len(signature) == 0 {
+		return fmt.Errorf("remote config has no valid %s header", signatureHeader)
+	}
+	if !hmac.Equal(signature, SignRemoteConfig(d.Body, []byte(secret))) {
+		return errors.New("remote config signature mismatch")
+	}
+	return nil
+}
+
+// SignRemoteConfig returns the HMAC-SHA256 of a remote config document, its hex encoding is the X-Config-Signature header
+func SignRemoteConfig(body, secret []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return mac.Sum(nil)
+}
+
+// readCache reads the cached document, it's verified again so a tampered cache is rejected
+func readCache(path, url, secret string) (*remoteDocument, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	doc := new(remoteDocument)
+	if err := json.Unmarshal(b, doc); err != nil {
+		return nil, err
+	}
+	if doc.URL != url {
+		return nil, fmt.Errorf("cached remote config is for %s", doc.URL)
+	}
+	if err := doc.verify(secret); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+func writeCache(path string, doc *remoteDocument) error {
+	b, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	// written to a temp file first, so a crash never leaves a truncated cache
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,17 @@
+package config
+
+type SchedulerConfig interface {
+	GetEnabled() bool
+}
+
+type schedulerConfig struct {
+	Enabled bool `yaml:"enabled" mapstructure:"enabled" default:"true" reload:"restart" desc:"Run the scheduled jobs of the modules, e.g. disabled on the replicas that shouldn't run them"`
+}
+
+func Scheduler() SchedulerConfig {
+	return Current().Scheduler()
+}
+
+func (s *schedulerConfig) GetEnabled() bool {
+	return s.Enabled
+}
@@ -0,0 +1,137 @@
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Schema returns the JSON Schema of the config files, generated from the config structs:
+// descriptions come from the `desc` tags, defaults from the `default` tags and constraints (enums, ranges) from the `validate` tags.
+// Unknown keys are rejected, like Loader.Load does.
+func Schema() ([]byte, error) {
+	schema := objectSchema(reflect.TypeOf(appConfig{}))
+	schema["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	schema["title"] = "starter-go configuration"
+	return json.MarshalIndent(schema, "", "  ")
+}
+
+func objectSchema(t reflect.Type) map[string]interface{} {
+	props := map[string]interface{}{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		props[keyName(f)] = fieldSchema(f)
+	}
+	return map[string]interface{}{
+		"type":                 "object",
+		"properties":           props,
+		"additionalProperties": false,
+	}
+}
+
+func fieldSchema(f reflect.StructField) map[string]interface{} {
+	var s map[string]interface{}
+	switch {
+	case f.Type.Kind() == reflect.Struct:
+		s = objectSchema(f.Type)
+	case isStructSlice(f.Type):
+		s = map[string]interface{}{"type": "array", "items": objectSchema(f.Type.Elem())}
+	case f.Type.Kind() == reflect.Slice:
+		s = map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": jsonType(f.Type.Elem())}}
+	default:
+		s = map[string]interface{}{"type": jsonType(f.Type)}
+		if isUnsigned(f.Type) {
+			s["minimum"] = 0
+		}
+	}
+
+	if desc := f.Tag.Get("desc"); desc != "" {
+		s["description"] = desc
+	}
+	if def, ok := f.Tag.Lookup("default"); ok && !isStructSlice(f.Type) {
+		s["default"] = schemaDefault(f.Type, def)
+	}
+	if isSecret(f) {
+		s["writeOnly"] = true
+	}
+
+	// rules after dive apply to the items of a slice
+	rules, itemRules, _ := strings.Cut(f.Tag.Get("validate"), ",dive")
+	applyRules(s, f.Type, rules)
+	if items, ok := s["items"].(map[string]interface{}); ok {
+		applyRules(items, f.Type.Elem(), strings.TrimPrefix(itemRules, ","))
+	}
+	return s
+}
+
+// applyRules translates the validator rules having a JSON Schema equivalent, the others (e.g. timezone) are only checked on load
+func applyRules(s map[string]interface{}, t reflect.Type, rules string) {
+	for _, rule := range strings.Split(rules, ",") {
+		name, param, _ := strings.Cut(rule, "=")
+		switch name {
+		case "oneof":
+			s["enum"] = strings.Fields(param)
+		case "min", "max":
+			n, err := strconv.Atoi(param)
+			if err != nil {
+				continue
+			}
+			switch {
+			case t.Kind() == reflect.Slice:
+				s[name+"Items"] = n
+			case t.Kind() == reflect.String:
+				s[name+"Length"] = n
+			default:
+				s[map[string]string{"min": "minimum", "max": "maximum"}[name]] = n
+			}
+		case "required":
+			if t.Kind() == reflect.String {
+				s["minLength"] = 1
+			}
+		case "url":
+			s["format"] = "uri"
+		}
+	}
+}
+
+func jsonType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	default:
+		return "string"
+	}
+}
+
+func isUnsigned(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
+
+// schemaDefault converts a `default` tag to the JSON type of the field
+func schemaDefault(t reflect.Type, def string) interface{} {
+	switch jsonType(t) {
+	case "boolean":
+		b, _ := strconv.ParseBool(def)
+		return b
+	case "integer":
+		n, _ := strconv.Atoi(def)
+		return n
+	}
+	if t.Kind() == reflect.Slice {
+		return defaultValue(t, def)
+	}
+	return def
+}
@@ -0,0 +1,202 @@
+package config
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+)
+
+// Prefixes of config values that are references to secrets, resolved on load:
+//
+//	file:///run/secrets/db_password  content of the file, trailing newline trimmed
+//	env:DB_PASSWORD                  value of the env var
+//	enc:<base64>                     value encrypted with EncryptSecret
+const (
+	refFile = "file://"
+	refEnv  = "env:"
+	refEnc  = "enc:"
+)
+
+// Env vars holding the key used to decrypt enc: values, either the base64 key itself or a file containing it
+const (
+	secretKeyEnv     = "APP_CONFIG_KEY"
+	secretKeyFileEnv = "APP_CONFIG_KEY_FILE"
+)
+
+// isRef reports whether value is a secret reference
+func isRef(value string) bool {
+	return strings.HasPrefix(value, refFile) || strings.HasPrefix(value, refEnv) || strings.HasPrefix(value, refEnc)
+}
+
+// resolveSecrets replaces every reference in the string fields of cfg with the secret it points to.
+// Resolved keys are recorded so they're redacted like fields tagged `secret:"true"`.
+// When resolve is false, the references are only checked and kept as is (see WithOffline).
+func resolveSecrets(cfg *appConfig, lookupEnv func(string) (string, bool), resolve bool, verr *ValidationError) {
+	cfg.resolved = map[string]string{}
+	resolveValue(reflect.ValueOf(cfg).Elem(), "", lookupEnv, resolve, cfg.resolved, verr)
+}
+
+func resolveValue(v reflect.Value, prefix string, lookupEnv func(string) (string, bool), resolve bool, resolved map[string]string, verr *ValidationError) {
+	for _, f := range leafFields(v.Type(), prefix) {
+		fv := fieldByKey(v, strings.TrimPrefix(strings.TrimPrefix(f.Key, prefix), "."))
+		switch {
+		case isStructSlice(f.Struct.Type):
+			for i := 0; i < fv.Len(); i++ {
+				resolveValue(fv.Index(i), fmt.Sprintf("%s[%d]", f.Key, i), lookupEnv, resolve, resolved, verr)
+			}
+		case fv.Kind() == reflect.String && isRef(fv.String()) && !resolve:
+			if err := checkRef(fv.String()); err != nil {
+				verr.add(f.Key, err.Error())
+				continue
+			}
+			resolved[f.Key] = refName(fv.String())
+		case fv.Kind() == reflect.String && isRef(fv.String()):
+			secret, err := resolveRef(fv.String(), lookupEnv)
+			if err != nil {
+				verr.add(f.Key, err.Error())
+				continue
+			}
+			resolved[f.Key] = refName(fv.String())
+			fv.SetString(secret)
+		}
+	}
+}
+
+// refName describes a reference without leaking the encrypted value, e.g. env:DB_PASSWORD or enc
+func refName(ref string) string {
+	if strings.HasPrefix(ref, refEnc) {
+		return strings.TrimSuffix(refEnc, ":")
+	}
+	return ref
+}
+
+// checkRef checks the syntax of a reference without reading the secret, which may only exist where the service runs
+func checkRef(ref string) error {
+	switch {
+	case ref == refFile || ref == refEnv:
+		return fmt.Errorf("secret reference %q has no name", ref)
+	case strings.HasPrefix(ref, refEnc):
+		if _, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(ref, refEnc)); err != nil {
+			return fmt.Errorf("encrypted value must be base64 encoded: %s", err)
+		}
+	}
+	return nil
+}
+
+func resolveRef(ref string, lookupEnv func(string) (string, bool)) (string, error) {
+	switch {
+	case strings.HasPrefix(ref, refFile):
+		path := strings.TrimPrefix(ref, refFile)
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("unable to read secret file %q: %s", path, err)
+		}
+		return strings.TrimRight(string(b), "\r\n"), nil
+
+	case strings.HasPrefix(ref, refEnv):
+		name := strings.TrimPrefix(ref, refEnv)
+		value, ok := lookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("secret env var %s is not set", name)
+		}
+		return value, nil
+
+	default:
+		key, err := loadSecretKey(lookupEnv)
+		if err != nil {
+			return "", err
+		}
+		return DecryptSecret(ref, key)
+	}
+}
+
+// LoadSecretKey reads the key used by enc: values from APP_CONFIG_KEY, or from the file named by APP_CONFIG_KEY_FILE
+func LoadSecretKey() ([]byte, error) {
+	return loadSecretKey(os.LookupEnv)
+}
+
+func loadSecretKey(lookupEnv func(string) (string, bool)) ([]byte, error) {
+	encoded, ok := lookupEnv(secretKeyEnv)
+	if !ok {
+		path, ok := lookupEnv(secretKeyFileEnv)
+		if !ok {
+			return nil, fmt.Errorf("%s or %s must be set to decrypt enc: values", secretKeyEnv, secretKeyFileEnv)
+		}
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read secret key file %q: %s", path, err)
+		}
+		encoded = string(b)
+	}
+
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("secret key must be base64 encoded: %s", err)
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("secret key must be 32 bytes, got %d", len(key))
+	}
+	return key, nil
+}
+
+// GenerateSecretKey returns a new random base64 encoded key for APP_CONFIG_KEY
+func GenerateSecretKey() (string, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// EncryptSecret encrypts plaintext with AES-256-GCM, the result can be used as a config value
+func EncryptSecret(plaintext string, key []byte) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	sealed := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
+	return refEnc + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// DecryptSecret decrypts a value produced by EncryptSecret
+func DecryptSecret(value string, key []byte) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, refEnc))
+	if err != nil {
+		return "", fmt.Errorf("encrypted value must be base64 encoded: %s", err)
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return "", errors.New("encrypted value is too short")
+	}
+
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", errors.New("unable to decrypt value, wrong key or corrupted value")
+	}
+	return string(plaintext), nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
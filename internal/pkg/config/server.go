@@ -1,43 +1,80 @@
 package config
 
+import (
+	"slices"
+	"time"
+)
+
 type ServerConfig interface {
 	GetTimeZone() string
+	GetLocation() *time.Location
 	GetLoglevel() string
-	GetEnvironment() string
+	GetEnvironment() Environment
 	GetBaseURL() string
 	GetReadTimeout() uint
 	GetWriteTimeout() uint
 	GetIdleTimeout() uint
+	GetTrustedProxies() []string
+	GetShutdownDelay() uint
+	GetShutdownGrace() uint
+	GetShutdownTimeout() uint
 	GetPort() uint
+	GetTLS() TLSConfig
+	GetListener() ListenerConfig
 }
 
 type serverConfig struct {
-	TimeZone     string `yaml:"time_zone" mapstructure:"time_zone"`
-	Loglevel     string `yaml:"loglevel" mapstructure:"loglevel"`
-	Environment  string `yaml:"env" mapstructure:"env"`
-	BaseURL      string `yaml:"base_url" mapstructure:"base_url"`
-	Port         uint   `yaml:"port" mapstructure:"port"`
-	ReadTimeout  uint   `yaml:"read_timeout" mapstructure:"read_timeout"`
-	WriteTimeout uint   `yaml:"write_timeout" mapstructure:"write_timeout"`
-	IdleTimeout  uint   `yaml:"idle_timeout" mapstructure:"idle_timeout"`
+	TimeZone       string      `yaml:"time_zone" mapstructure:"time_zone" default:"UTC" reload:"restart" validate:"timezone" desc:"IANA time zone of logs, database timestamps and responses"`
+	Loglevel       string      `yaml:"loglevel" mapstructure:"loglevel" default:"INFO" validate:"oneof=DEBUG INFO WARN ERROR OFF" desc:"Minimum level of logs"`
+	Environment    Environment `yaml:"env" mapstructure:"env" default:"local" reload:"restart" validate:"oneof=local development staging production" desc:"Deployment environment, selects the config overlay (e.g. config.production.yaml)"`
+	BaseURL        string      `yaml:"base_url" mapstructure:"base_url" default:"http://localhost:8000" reload:"restart" validate:"url" desc:"Public URL of the service"`
+	Port           uint        `yaml:"port" mapstructure:"port" default:"8000" reload:"restart" validate:"min=1,max=65535" desc:"HTTP port to listen on"`
+	ReadTimeout    uint        `yaml:"read_timeout" mapstructure:"read_timeout" default:"10000" reload:"restart" validate:"min=1" desc:"Maximum duration for reading a request, in ms"`                                   // in ms
+	WriteTimeout   uint        `yaml:"write_timeout" mapstructure:"write_timeout" default:"65000" reload:"restart" validate:"min=1" desc:"Maximum duration before timing out writes of the response, in ms"`              // in ms
+	IdleTimeout    uint        `yaml:"idle_timeout" mapstructure:"idle_timeout" default:"60000" reload:"restart" validate:"min=1" desc:"Maximum duration to wait for the next request on a keep-alive connection, in ms"` // in ms
+	TrustedProxies []string    `yaml:"trusted_proxies" mapstructure:"trusted_proxies" reload:"restart" validate:"dive,cidr|ip" desc:"Proxies (IPs or CIDRs) whose X-Forwarded-For is trusted for the client IP, e.g. the load balancer, empty uses the address of the connection"`
+
+	// shutdown sequence, see README
+	ShutdownDelay   uint `yaml:"shutdown_delay" mapstructure:"shutdown_delay" default:"0" desc:"Time between turning the readiness down and closing the listeners on shutdown, for the load balancers to stop routing traffic, in ms"`               // in ms
+	ShutdownGrace   uint `yaml:"shutdown_grace" mapstructure:"shutdown_grace" default:"20000" reload:"restart" validate:"ltfield=ShutdownTimeout" desc:"Time in-flight requests have to finish on shutdown before their context is canceled, in ms"` // in ms
+	ShutdownTimeout uint `yaml:"shutdown_timeout" mapstructure:"shutdown_timeout" default:"30000" validate:"min=1" desc:"Maximum duration to stop the server and the apps on shutdown, after the shutdown delay, in ms"`                             // in ms
+
+	TLS      tlsConfig      `yaml:"tls" mapstructure:"tls" desc:"HTTPS and mutual TLS, see README"`
+	Listener listenerConfig `yaml:"listener" mapstructure:"listener" desc:"Where the server listens: TCP, a unix socket or a socket passed by systemd, see README"`
+
+	// parsed from TimeZone on load
+	location *time.Location
 }
 
 func Server() ServerConfig {
-	return &cfg.Server
+	return Current().Server()
 }
 func (server *serverConfig) GetPort() uint {
 	return server.Port
 }
 
+func (server *serverConfig) GetTLS() TLSConfig {
+	return &server.TLS
+}
+
+func (server *serverConfig) GetListener() ListenerConfig {
+	return &server.Listener
+}
+
 func (server *serverConfig) GetTimeZone() string {
 	return server.TimeZone
 }
 
+// GetLocation returns the location of time_zone, used for log timestamps, the database and responses
+func (server *serverConfig) GetLocation() *time.Location {
+	return server.location
+}
+
 func (server *serverConfig) GetLoglevel() string {
 	return server.Loglevel
 }
 
-func (server *serverConfig) GetEnvironment() string {
+func (server *serverConfig) GetEnvironment() Environment {
 	return server.Environment
 }
 
@@ -56,3 +93,19 @@ func (server *serverConfig) GetWriteTimeout() uint {
 func (server *serverConfig) GetIdleTimeout() uint {
 	return server.IdleTimeout
 }
+
+func (server *serverConfig) GetTrustedProxies() []string {
+	return slices.Clone(server.TrustedProxies)
+}
+
+func (server *serverConfig) GetShutdownDelay() uint {
+	return server.ShutdownDelay
+}
+
+func (server *serverConfig) GetShutdownGrace() uint {
+	return server.ShutdownGrace
+}
+
+func (server *serverConfig) GetShutdownTimeout() uint {
+	return server.ShutdownTimeout
+}
@@ -0,0 +1,17 @@
+package config
+
+type StorageConfig interface {
+	GetBackend() string
+}
+
+type storageConfig struct {
+	Backend string `yaml:"backend" mapstructure:"backend" default:"mysql" reload:"restart" validate:"oneof=memory mysql postgres" desc:"Storage backend of the repositories, memory doesn't open a database"`
+}
+
+func Storage() StorageConfig {
+	return Current().Storage()
+}
+
+func (s *storageConfig) GetBackend() string {
+	return s.Backend
+}
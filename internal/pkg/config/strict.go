@@ -0,0 +1,105 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// keyIndex is the position of every key of a config file, used to report errors with line numbers
+type keyIndex struct {
+	lines map[string]int
+	// keys that don't match any config field, e.g. a typo like logger.enable_logfiles
+	unknown []string
+}
+
+// indexFile parses the config file at path and indexes its keys, viper can't tell which keys it ignored
+func indexFile(path string) (*keyIndex, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading config file, %s", err)
+	}
+	return indexDocument(path, b)
+}
+
+// indexDocument indexes the keys of a YAML (or JSON) config document, name is only used in errors
+func indexDocument(name string, b []byte) (*keyIndex, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		return nil, fmt.Errorf("error parsing config %s, %s", name, err)
+	}
+
+	idx := &keyIndex{lines: map[string]int{}}
+	if len(doc.Content) > 0 {
+		idx.walk(doc.Content[0], reflect.TypeOf(appConfig{}), "")
+	}
+	return idx, nil
+}
+
+// walk indexes the keys of a mapping node decoded into a struct of type t
+func (idx *keyIndex) walk(node *yaml.Node, t reflect.Type, prefix string) {
+	if node.Kind != yaml.MappingNode {
+		return
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		name, value := node.Content[i], node.Content[i+1]
+		key := strings.TrimPrefix(prefix+"."+name.Value, ".")
+		idx.lines[key] = name.Line
+
+		f, ok := fieldByName(t, name.Value)
+		if !ok {
+			idx.unknown = append(idx.unknown, key)
+			continue
+		}
+
+		switch {
+		case f.Type.Kind() == reflect.Struct:
+			idx.walk(value, f.Type, key)
+		case isStructSlice(f.Type) && value.Kind == yaml.SequenceNode:
+			for j, item := range value.Content {
+				elem := fmt.Sprintf("%s[%d]", key, j)
+				idx.lines[elem] = item.Line
+				idx.walk(item, f.Type.Elem(), elem)
+			}
+		}
+	}
+}
+
+// fieldByName finds the field of t matching a config key, case-insensitive like viper
+func fieldByName(t reflect.Type, name string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.IsExported() && strings.EqualFold(keyName(f), name) {
+			return f, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// locate sets the file (or remote URL) and line of every error whose key was read from one of the indexed documents
+func (e *ValidationError) locate(sources map[string]Source, indexes map[string]*keyIndex) {
+	for i, fe := range e.Errors {
+		if fe.File != "" {
+			continue
+		}
+		// slice elements share the source of their slice, e.g. logger.logfile_configs[0].max_size
+		base, _, _ := strings.Cut(fe.Key, "[")
+		src, ok := sources[base]
+		if !ok || (src.Kind != SourceFile && src.Kind != SourceRemote) {
+			continue
+		}
+		lines := indexes[src.Name].lines
+		line, ok := lines[fe.Key]
+		if !ok && strings.HasSuffix(fe.Key, "]") {
+			// items of a list of values (e.g. levels[0]) aren't indexed, use the line of the list
+			line, ok = lines[fe.Key[:strings.LastIndex(fe.Key, "[")]]
+		}
+		if ok {
+			e.Errors[i].File, e.Errors[i].Line = src.Name, line
+		}
+	}
+}
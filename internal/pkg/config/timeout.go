@@ -0,0 +1,33 @@
+package config
+
+type TimeoutConfig interface {
+	GetDefault() uint
+	GetRoutes() map[string]uint
+}
+
+type routeTimeoutConfig struct {
+	Path    string `yaml:"path" mapstructure:"path" validate:"required,startswith=/" desc:"Route group the timeout applies to, a path prefix of the routes, e.g. /api/v1/examples"`
+	Timeout uint   `yaml:"timeout" mapstructure:"timeout" desc:"Timeout of the requests of the route group, in ms, 0 disables it"` // in ms
+}
+
+type timeoutConfig struct {
+	Default uint                 `yaml:"default" mapstructure:"default" default:"30000" desc:"Timeout of the requests of the public server, in ms, 0 disables it. Keep it below server.write_timeout so the 504 is written"` // in ms
+	Routes  []routeTimeoutConfig `yaml:"routes" mapstructure:"routes" validate:"unique=Path,dive" desc:"Timeouts of route groups overriding the default, the longest matching path applies"`
+}
+
+func Timeout() TimeoutConfig {
+	return Current().Timeout()
+}
+
+func (t *timeoutConfig) GetDefault() uint {
+	return t.Default
+}
+
+// GetRoutes returns the timeout of every route group, keyed by path
+func (t *timeoutConfig) GetRoutes() map[string]uint {
+	routes := make(map[string]uint, len(t.Routes))
+	for _, r := range t.Routes {
+		routes[r.Path] = r.Timeout
+	}
+	return routes
+}
@@ -0,0 +1,97 @@
+package config
+
+import (
+	"crypto/tls"
+	"slices"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+type TLSConfig interface {
+	GetEnabled() bool
+	GetCertFile() string
+	GetKeyFile() string
+	GetMinVersion() uint16
+	GetCipherSuites() []uint16
+	GetClientAuth() tls.ClientAuthType
+	GetClientCA() string
+	GetReloadInterval() uint
+}
+
+type tlsConfig struct {
+	Enabled        bool     `yaml:"enabled" mapstructure:"enabled" default:"false" reload:"restart" desc:"Serve HTTPS instead of HTTP on server.port"`
+	CertFile       string   `yaml:"cert_file" mapstructure:"cert_file" reload:"restart" validate:"required_if=Enabled true" desc:"PEM certificate of the server, followed by the intermediates, reloaded when it changes"`
+	KeyFile        string   `yaml:"key_file" mapstructure:"key_file" reload:"restart" validate:"required_if=Enabled true" desc:"PEM private key of the certificate, reloaded when it changes"`
+	MinVersion     string   `yaml:"min_version" mapstructure:"min_version" default:"1.2" reload:"restart" validate:"oneof=1.2 1.3" desc:"Minimum TLS version"`
+	CipherSuites   []string `yaml:"cipher_suites" mapstructure:"cipher_suites" reload:"restart" validate:"dive,cipher_suite" desc:"Cipher suites of TLS 1.2 by name (e.g. TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256), empty uses the Go defaults, TLS 1.3 suites aren't configurable"`
+	ClientAuth     string   `yaml:"client_auth" mapstructure:"client_auth" default:"none" reload:"restart" validate:"oneof=none optional required" desc:"Mutual TLS: none, optional verifies the client certificates sent, required rejects the clients without one"`
+	ClientCA       string   `yaml:"client_ca" mapstructure:"client_ca" reload:"restart" validate:"required_unless=ClientAuth none" desc:"PEM certificates of the CAs client certificates are verified against, reloaded when it changes"`
+	ReloadInterval uint     `yaml:"reload_interval" mapstructure:"reload_interval" default:"10000" reload:"restart" desc:"How often the certificate files are checked for changes, on the next handshake, in ms, 0 checks on every handshake"` // in ms
+}
+
+func (t *tlsConfig) GetEnabled() bool {
+	return t.Enabled
+}
+
+func (t *tlsConfig) GetCertFile() string {
+	return t.CertFile
+}
+
+func (t *tlsConfig) GetKeyFile() string {
+	return t.KeyFile
+}
+
+// GetMinVersion returns the crypto/tls constant of min_version
+func (t *tlsConfig) GetMinVersion() uint16 {
+	if t.MinVersion == "1.3" {
+		return tls.VersionTLS13
+	}
+	return tls.VersionTLS12
+}
+
+// GetCipherSuites returns the IDs of cipher_suites, nil for the Go defaults
+func (t *tlsConfig) GetCipherSuites() []uint16 {
+	var ids []uint16
+	for _, name := range t.CipherSuites {
+		if id, ok := cipherSuiteID(name); ok {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// GetClientAuth returns the crypto/tls mode of client_auth
+func (t *tlsConfig) GetClientAuth() tls.ClientAuthType {
+	switch t.ClientAuth {
+	case "optional":
+		return tls.VerifyClientCertIfGiven
+	case "required":
+		return tls.RequireAndVerifyClientCert
+	default:
+		return tls.NoClientCert
+	}
+}
+
+func (t *tlsConfig) GetClientCA() string {
+	return t.ClientCA
+}
+
+func (t *tlsConfig) GetReloadInterval() uint {
+	return t.ReloadInterval
+}
+
+// cipherSuiteID returns the ID of a secure TLS 1.2 cipher suite implemented by crypto/tls
+func cipherSuiteID(name string) (uint16, bool) {
+	for _, s := range tls.CipherSuites() {
+		if strings.EqualFold(s.Name, name) && slices.Contains(s.SupportedVersions, tls.VersionTLS12) {
+			return s.ID, true
+		}
+	}
+	return 0, false
+}
+
+func isCipherSuite(fl validator.FieldLevel) bool {
+	_, ok := cipherSuiteID(fl.Field().String())
+	return ok
+}
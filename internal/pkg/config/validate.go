@@ -0,0 +1,294 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"unicode"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/go-viper/mapstructure/v2"
+
+	"starter-go/internal/pkg/flags"
+	"starter-go/internal/pkg/ratelimit"
+)
+
+// FieldError is a single invalid configuration key
+type FieldError struct {
+	Key     string
+	Env     string
+	Message string
+	// File and Line locate the key when its value was read from a config file
+	File string
+	Line int
+}
+
+func (e FieldError) String() string {
+	s := fmt.Sprintf("%s: %s", e.Key, e.Message)
+	if e.Env != "" {
+		s = fmt.Sprintf("%s (%s): %s", e.Key, e.Env, e.Message)
+	}
+	if e.File != "" {
+		s = fmt.Sprintf("%s:%d: %s", e.File, e.Line, s)
+	}
+	return s
+}
+
+// ValidationError aggregates every problem found while loading the configuration,
+// so all of them can be fixed at once instead of one restart at a time.
+type ValidationError struct {
+	Errors []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	var sb strings.Builder
+	sb.WriteString("invalid configuration:")
+	for _, fe := range e.Errors {
+		sb.WriteString("\n  - ")
+		sb.WriteString(fe.String())
+	}
+	return sb.String()
+}
+
+func (e *ValidationError) add(key, message string) {
+	e.Errors = append(e.Errors, FieldError{Key: key, Env: envName(key), Message: message})
+}
+
+func (e *ValidationError) has(key string) bool {
+	for _, fe := range e.Errors {
+		if fe.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New(validator.WithRequiredStructEnabled())
+
+	// report mapstructure keys (server.port) instead of go field names (Server.Port)
+	v.RegisterTagNameFunc(func(f reflect.StructField) string {
+		return keyName(f)
+	})
+
+	_ = v.RegisterValidation("logfile_path", isLogfilePath)
+	_ = v.RegisterValidation("cipher_suite", isCipherSuite)
+	_ = v.RegisterValidation("file_mode", isFileMode)
+	v.RegisterStructValidation(validateLogger, loggerConfig{})
+
+	return v
+}
+
+// validateConfig checks every `validate` tag of cfg and appends the failures to verr.
+// Keys already in verr (i.e. failed to decode) are not reported twice.
+// It returns verr when it holds any error.
+func validateConfig(cfg *appConfig, verr *ValidationError) error {
+	err := validate.Struct(cfg)
+
+	var fieldErrs validator.ValidationErrors
+	if err != nil && !errors.As(err, &fieldErrs) {
+		return err
+	}
+
+	for _, fe := range fieldErrs {
+		// namespace is prefixed by the root struct name, e.g. appConfig.server.port
+		_, key, _ := strings.Cut(fe.Namespace(), ".")
+		if verr.has(key) {
+			continue
+		}
+		value := fmt.Sprint(fe.Value())
+		if cfg.resolved[key] != "" {
+			value = redacted
+		}
+		verr.add(key, validationMessage(fe, value))
+	}
+	validatePorts(cfg, verr)
+	validateRateLimit(cfg, verr)
+	validateFlags(cfg, verr)
+
+	if len(verr.Errors) == 0 {
+		return nil
+	}
+	return verr
+}
+
+// collectDecodeErrors flattens the joined errors returned by viper.Unmarshal into verr
+func collectDecodeErrors(err error, verr *ValidationError) {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		for _, e := range joined.Unwrap() {
+			collectDecodeErrors(e, verr)
+		}
+		return
+	}
+
+	var de *mapstructure.DecodeError
+	if errors.As(err, &de) {
+		verr.add(de.Name(), de.Unwrap().Error())
+		return
+	}
+	verr.add("", err.Error())
+}
+
+// validationMessage describes the failed rule, value is the display value (redacted for secrets)
+func validationMessage(fe validator.FieldError, value string) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "required_if":
+		field, value, _ := strings.Cut(fe.Param(), " ")
+		return fmt.Sprintf("is required when %s is %s", snakeCase(field), value)
+	case "required_unless":
+		field, value, _ := strings.Cut(fe.Param(), " ")
+		return fmt.Sprintf("is required unless %s is %s", snakeCase(field), value)
+	case "min":
+		if fe.Kind() == reflect.Slice {
+			return fmt.Sprintf("must have at least %s item(s)", fe.Param())
+		}
+		return fmt.Sprintf("must be at least %s", fe.Param())
+	case "max":
+		return fmt.Sprintf("must be at most %s", fe.Param())
+	case "oneof":
+		return fmt.Sprintf("must be one of [%s], got %q", fe.Param(), value)
+	case "timezone":
+		return fmt.Sprintf("must be a valid IANA time zone, got %q", value)
+	case "unique":
+		return fmt.Sprintf("must not have duplicate %s", strings.ToLower(fe.Param()))
+	case "ltfield":
+		return fmt.Sprintf("must be less than %s", snakeCase(fe.Param()))
+	case "url":
+		return fmt.Sprintf("must be a valid URL, got %q", value)
+	case "cidr|ip":
+		return fmt.Sprintf("must be an IP or a CIDR, got %q", value)
+	case "startswith":
+		return fmt.Sprintf("must start with %q, got %q", fe.Param(), value)
+	case "cipher_suite":
+		return fmt.Sprintf("must be a TLS 1.2 cipher suite of crypto/tls, e.g. TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256, got %q", value)
+	case "file_mode":
+		return fmt.Sprintf("must be octal permissions, e.g. 0660, got %q", value)
+	case "logfile_path":
+		return fmt.Sprintf("must be a writable file path, got %q", value)
+	case "stdout_or_logfile":
+		return "enable_stdout or enable_logfile must be true"
+	case "logfile_configs":
+		return "must not be empty when enable_logfile is true"
+	default:
+		return fmt.Sprintf("failed on the %q rule", fe.Tag())
+	}
+}
+
+// snakeCase converts the Go name of a sibling field (e.g. ShutdownTimeout) to its key (shutdown_timeout)
+func snakeCase(name string) string {
+	var b strings.Builder
+	for i, r := range name {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func validateLogger(sl validator.StructLevel) {
+	conf := sl.Current().Interface().(loggerConfig)
+	if !conf.EnableStdout && !conf.EnableLogFile {
+		sl.ReportError(conf.EnableStdout, "enable_stdout", "EnableStdout", "stdout_or_logfile", "")
+	}
+	if conf.EnableLogFile && len(conf.LogFileConfigs) == 0 {
+		sl.ReportError(conf.LogFileConfigs, "logfile_configs", "LogFileConfigs", "logfile_configs", "")
+	}
+}
+
+// validatePorts checks that the admin server doesn't listen on the port of the public server,
+// the tags only compare fields of the same section
+func validatePorts(cfg *appConfig, verr *ValidationError) {
+	if !cfg.Admin.Enabled || cfg.Server.Listener.Network != "tcp" || verr.has("admin.port") {
+		return
+	}
+	if cfg.Admin.Port == cfg.Server.Port {
+		verr.add("admin.port", fmt.Sprintf("must differ from server.port, both are %d", cfg.Admin.Port))
+	}
+}
+
+// validateRateLimit checks that the policies keyed by api_key have API keys to check the requests against
+func validateRateLimit(cfg *appConfig, verr *ValidationError) {
+	if len(cfg.RateLimit.APIKeys) > 0 {
+		return
+	}
+	for i, policy := range cfg.RateLimit.Policies {
+		key := fmt.Sprintf("ratelimit.policies[%d].key_by", i)
+		if policy.KeyBy == ratelimit.KeyByAPIKey && !verr.has(key) {
+			verr.add(key, "api_key requires ratelimit.api_keys, the API keys the requests are checked against")
+		}
+	}
+}
+
+// validateFlags checks that the flags rolled out by user_id have a user to key on,
+// the user id is the verified client certificate so mutual TLS is required
+func validateFlags(cfg *appConfig, verr *ValidationError) {
+	tls := cfg.Server.TLS
+	if tls.Enabled && tls.ClientAuth != "none" {
+		return
+	}
+	for i, flag := range cfg.Flags {
+		key := fmt.Sprintf("flags[%d].rollout_by", i)
+		if flag.RolloutBy == flags.RolloutByUserID && !verr.has(key) {
+			verr.add(key, "user_id requires server.tls.client_auth, the user id is the name of the verified client certificate")
+		}
+	}
+}
+
+// isLogfilePath checks that the log file can be opened for writing, or created by lumberjack.
+// Paths are only checked when logger.enable_logfile is set.
+func isLogfilePath(fl validator.FieldLevel) bool {
+	if cfg, ok := fl.Top().Interface().(*appConfig); ok && !cfg.Logger.EnableLogFile {
+		return true
+	}
+
+	path := fl.Field().String()
+	if path == "" {
+		return false
+	}
+
+	if info, err := os.Stat(path); err == nil {
+		if info.IsDir() {
+			return false
+		}
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+		if err != nil {
+			return false
+		}
+		return f.Close() == nil
+	}
+
+	// lumberjack creates missing directories, so check the closest existing one
+	dir := filepath.Dir(path)
+	for {
+		info, err := os.Stat(dir)
+		if err == nil {
+			if !info.IsDir() {
+				return false
+			}
+			break
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return false
+		}
+		dir = parent
+	}
+
+	f, err := os.CreateTemp(dir, ".writable-*")
+	if err != nil {
+		return false
+	}
+	f.Close()
+	return os.Remove(f.Name()) == nil
+}
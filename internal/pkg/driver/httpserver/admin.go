@@ -0,0 +1,24 @@
+package httpserver
+
+import (
+	"net"
+	"strconv"
+
+	"starter-go/internal/pkg/config"
+	mw "starter-go/internal/pkg/driver/httpserver/middleware"
+)
+
+// NewAdminServer returns the server of the ops endpoints, listening on admin.host and admin.port with the timeouts of the public server.
+// Its engine only has the recovery and error handling, the routes set their authentication.
+// The requests still running on Stop, e.g. a CPU profile, are canceled right away.
+func NewAdminServer(conf config.AdminConfig, serverConf config.ServerConfig) server {
+	addr := net.JoinHostPort(conf.GetHost(), strconv.FormatUint(uint64(conf.GetPort()), 10))
+	srv := newServer("admin", addr, serverConf, 0)
+
+	srv.e.Use(mw.CustomRecovery())
+	srv.e.Use(mw.InFlight(srv.inFlight))
+	srv.e.Use(mw.RequestTimer())
+	srv.e.Use(mw.ErrorHandler())
+
+	return srv
+}
@@ -4,66 +4,170 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
-	"os"
+	"sync/atomic"
 	"time"
 
 	"github.com/gin-gonic/gin"
 
 	"starter-go/internal/pkg/config"
 	mw "starter-go/internal/pkg/driver/httpserver/middleware"
+	"starter-go/internal/pkg/listener"
+	"starter-go/internal/pkg/logger"
 )
 
 type server struct {
-	s *http.Server
-	e *gin.Engine
+	// name is the name of the server in the app logs
+	name string
+	s    *http.Server
+	e    *gin.Engine
+	cors *mw.CORSMiddleware
+	// failed receives the error of Serve, buffered so the serving goroutine never blocks
+	failed chan error
+	// inFlight is the number of requests being served
+	inFlight *atomic.Int64
+	// grace is how long in-flight requests have to finish on Stop before cancelRequests is called
+	grace time.Duration
+	// cancelRequests cancels the context of every request, it's the base context of the server
+	cancelRequests context.CancelFunc
+	// tls is set when the server serves HTTPS
+	tls config.TLSConfig
+	// listener is the network the server listens on, TCP when nil
+	listener config.ListenerConfig
 	// port         string
 	// readTimeout  time.Duration
 	// writeTimeout time.Duration
 }
 
-func NewServer() server {
-	router := gin.New()
+func NewServer(conf config.ServerConfig, corsConf config.CORSConfig) server {
+	cors, err := mw.NewCORS(corsConf.GetAllowOrigins())
+	if err != nil {
+		log.Fatalf("Invalid CORS configuration: %v", err)
+	}
+
+	srv := newServer("http", fmt.Sprintf(":%d", conf.GetPort()), conf, time.Duration(conf.GetShutdownGrace())*time.Millisecond)
+	srv.cors = cors
+	srv.listener = conf.GetListener()
+	if conf.GetTLS().GetEnabled() {
+		srv.tls = conf.GetTLS()
+	}
 
 	// recovery middleware
-	router.Use(mw.CustomRecovery())
+	srv.e.Use(mw.CustomRecovery())
 
 	// custom middlewares
-	router.Use(mw.RequestTimer())
-	router.Use(mw.CORS())
-	router.Use(mw.Headers())
-	router.Use(mw.ErrorHandler())
+	srv.e.Use(mw.InFlight(srv.inFlight))
+	srv.e.Use(mw.ClientCert())
+	srv.e.Use(mw.RequestTimer())
+	srv.e.Use(cors.Handler())
+	srv.e.Use(mw.Headers())
+	srv.e.Use(mw.ErrorHandler())
+
+	return srv
+}
+
+// newServer returns a server listening on addr with the timeouts of conf, its engine has no middleware yet
+func newServer(name, addr string, conf config.ServerConfig, grace time.Duration) server {
+	router := gin.New()
+	// the client IP is the address of the connection unless it's a trusted proxy
+	if err := router.SetTrustedProxies(conf.GetTrustedProxies()); err != nil {
+		log.Fatalf("Invalid trusted proxies: %v", err)
+	}
+	baseCtx, cancelRequests := context.WithCancel(context.Background())
 
 	s := &http.Server{
-		Addr:         fmt.Sprintf(":%d", config.Server().GetPort()),
+		Addr:         addr,
 		Handler:      router,
-		ReadTimeout:  time.Duration(config.Server().GetReadTimeout()) * time.Millisecond,
-		WriteTimeout: time.Duration(config.Server().GetWriteTimeout()) * time.Millisecond,
-		IdleTimeout:  time.Duration(config.Server().GetIdleTimeout()) * time.Millisecond,
+		ReadTimeout:  time.Duration(conf.GetReadTimeout()) * time.Millisecond,
+		WriteTimeout: time.Duration(conf.GetWriteTimeout()) * time.Millisecond,
+		IdleTimeout:  time.Duration(conf.GetIdleTimeout()) * time.Millisecond,
+		BaseContext: func(net.Listener) context.Context {
+			return baseCtx
+		},
 	}
 
-	srv := server{
-		s: s,
-		e: router,
+	return server{
+		name:           name,
+		s:              s,
+		e:              router,
+		failed:         make(chan error, 1),
+		inFlight:       new(atomic.Int64),
+		grace:          grace,
+		cancelRequests: cancelRequests,
 	}
+}
 
-	return srv
+// UpdateCORS replaces the allowed origins, e.g. on config reload.
+// The current origins are kept when the new ones are invalid.
+func (srv server) UpdateCORS(conf config.CORSConfig) error {
+	return srv.cors.SetAllowOrigins(conf.GetAllowOrigins())
 }
 
 func (srv server) Engine() *gin.Engine {
 	return srv.e
 }
 
-func (srv server) Start() {
-	if err := srv.s.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-		log.Fatalf("Failed to start server: %v", err)
-		os.Exit(1)
+// Name is the name of the server in the app logs
+func (srv server) Name() string {
+	return srv.name
+}
+
+// Start loads the certificates when serving HTTPS and opens the listener, see listener.Listen, a failure is returned, then serves in the background
+func (srv server) Start(ctx context.Context) error {
+	serve := srv.s.Serve
+	if srv.tls != nil {
+		certs, err := newCertReloader(srv.tls)
+		if err != nil {
+			return err
+		}
+		srv.s.TLSConfig = certs.TLSConfig()
+		serve = func(ln net.Listener) error {
+			return srv.s.ServeTLS(ln, "", "")
+		}
+	}
+
+	ln, err := listener.Listen(ctx, srv.name, srv.listener, srv.s.Addr)
+	if err != nil {
+		return err
 	}
+
+	go func() {
+		if err := serve(ln); err != nil && err != http.ErrServerClosed {
+			srv.failed <- err
+		}
+	}()
+	return nil
 }
 
-func (srv server) Stop() {
-	ctx := context.Background()
+// Failed receives the error of the server failing after it started
+func (srv server) Failed() <-chan error {
+	return srv.failed
+}
+
+// InFlight returns the number of requests being served
+func (srv server) InFlight() int64 {
+	return srv.inFlight.Load()
+}
+
+// Stop closes the listeners and waits for the in-flight requests until ctx is done, the remaining connections are then closed.
+// The context of the requests still running after the grace period is canceled, so they can give up.
+func (srv server) Stop(ctx context.Context) error {
+	start := time.Now()
+	logger.Info("[Shutdown] closing listeners, draining in-flight requests", "server", srv.name, "in_flight", srv.InFlight())
+
+	grace := time.AfterFunc(srv.grace, func() {
+		logger.Warn("[Shutdown] grace period over, canceling in-flight requests", "server", srv.name, "in_flight", srv.InFlight(), "grace", srv.grace.String())
+		srv.cancelRequests()
+	})
+	defer grace.Stop()
+	defer srv.cancelRequests()
+
 	if err := srv.s.Shutdown(ctx); err != nil {
-		log.Fatalf("Server forced to shutdown: %v", err)
+		logger.Error("[Shutdown] in-flight requests didn't finish, closing connections", "server", srv.name, "in_flight", srv.InFlight(), "duration", time.Since(start).String())
+		_ = srv.s.Close()
+		return err
 	}
+	logger.Info("[Shutdown] in-flight requests drained", "server", srv.name, "duration", time.Since(start).String())
+	return nil
 }
@@ -0,0 +1,94 @@
+package httpserver
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"starter-go/internal/pkg/config"
+)
+
+type testServerConfig struct {
+	config.ServerConfig
+	port  uint
+	grace uint
+	tls   testTLSConfig
+}
+
+func (c testServerConfig) GetPort() uint               { return c.port }
+func (c testServerConfig) GetReadTimeout() uint        { return 1000 }
+func (c testServerConfig) GetWriteTimeout() uint       { return 5000 }
+func (c testServerConfig) GetIdleTimeout() uint        { return 1000 }
+func (c testServerConfig) GetTrustedProxies() []string { return nil }
+func (c testServerConfig) GetShutdownGrace() uint      { return c.grace }
+func (c testServerConfig) GetTLS() config.TLSConfig {
+	return c.tls
+}
+func (c testServerConfig) GetListener() config.ListenerConfig { return nil }
+
+type testCORSConfig struct{}
+
+func (testCORSConfig) GetAllowOrigins() []string { return []string{"*"} }
+
+func TestStopDrainsRequests(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	srv := NewServer(testServerConfig{port: 18097, grace: 100}, testCORSConfig{})
+
+	started := make(chan struct{})
+	srv.Engine().GET("/slow", func(c *gin.Context) {
+		close(started)
+		// a long running request, it gives up when its context is canceled
+		select {
+		case <-c.Request.Context().Done():
+			c.Status(http.StatusServiceUnavailable)
+		case <-time.After(10 * time.Second):
+			c.Status(http.StatusOK)
+		}
+	})
+
+	if err := srv.Start(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	status := make(chan int, 1)
+	go func() {
+		resp, err := http.Get("http://127.0.0.1:18097/slow")
+		if err != nil {
+			status <- 0
+			return
+		}
+		resp.Body.Close()
+		status <- resp.StatusCode
+	}()
+	<-started
+
+	if n := srv.InFlight(); n != 1 {
+		t.Errorf("expected 1 in-flight request, got %d", n)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	start := time.Now()
+	if err := srv.Stop(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// the request was canceled after the grace period, and could still respond
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("expected the request to be canceled after the grace period, stop took %s", elapsed)
+	}
+	if got := <-status; got != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 from the canceled request, got %d", got)
+	}
+	if n := srv.InFlight(); n != 0 {
+		t.Errorf("expected no in-flight request, got %d", n)
+	}
+
+	// the listener is closed
+	if _, err := http.Get("http://127.0.0.1:18097/slow"); err == nil {
+		t.Error("expected new connections to be refused")
+	}
+}
@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"starter-go/internal/pkg/clientcert"
+	"starter-go/internal/pkg/errors"
+	"starter-go/internal/pkg/logger/ctxfield"
+)
+
+// ClientCert puts the identity of the verified client certificate in the request context, see clientcert.FromContext,
+// and names the client in the logs of the request. It does nothing for the requests without one.
+func ClientCert() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.TLS == nil || len(c.Request.TLS.PeerCertificates) == 0 {
+			c.Next()
+			return
+		}
+
+		id := clientcert.New(c.Request.TLS.PeerCertificates[0])
+		ctx := clientcert.NewContext(GetContext(c), id)
+		c.Set(contextKey, ctx)
+		c.Request = c.Request.WithContext(clientcert.NewContext(c.Request.Context(), id))
+
+		ctxfield.Set(ctx, "client", id.Name())
+		ctxfield.Set(ctx, "client_serial", id.SerialNumber)
+
+		c.Next()
+	}
+}
+
+// RequireClientCert rejects the requests without a client certificate with 401, for the routes needing one when server.tls.client_auth is optional.
+// It's used after ClientCert.
+func RequireClientCert() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := clientcert.FromContext(GetContext(c)); !ok {
+			c.Error(errors.ErrUnauthorized("client certificate required"))
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// ClientCertSubject returns the name of the verified client certificate, empty without one, e.g. to rate limit by client
+func ClientCertSubject(c *gin.Context) string {
+	if id, ok := clientcert.FromContext(GetContext(c)); ok {
+		return id.Name()
+	}
+	return ""
+}
@@ -5,6 +5,7 @@ import (
 	"net/http"
 
 	"starter-go/internal/pkg/logger/contextid"
+	"starter-go/internal/pkg/logger/ctxfield"
 
 	"github.com/gin-gonic/gin"
 )
@@ -43,9 +44,10 @@ func GetContext(c *gin.Context) context.Context {
 }
 
 func newContext(req *http.Request) context.Context {
+	ctx := ctxfield.New(context.Background())
 	if requestID := req.Header.Get(requestHeaderRequestID); requestID != "" {
-		return contextid.NewWithValue(context.Background(), requestID)
+		return contextid.NewWithValue(ctx, requestID)
 	} else {
-		return contextid.New(context.Background())
+		return contextid.New(ctx)
 	}
 }
@@ -1,20 +1,50 @@
 package middleware
 
 import (
+	"sync/atomic"
 	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 )
 
-func CORS() gin.HandlerFunc {
+// CORSMiddleware is a CORS handler whose allowed origins can be replaced at runtime, e.g. on config reload
+type CORSMiddleware struct {
+	handler atomic.Pointer[gin.HandlerFunc]
+}
+
+// NewCORS creates the CORS middleware, "*" allows every origin.
+// Origins can contain a single wildcard, e.g. https://*.example.com
+func NewCORS(allowOrigins []string) (*CORSMiddleware, error) {
+	m := &CORSMiddleware{}
+	if err := m.SetAllowOrigins(allowOrigins); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// SetAllowOrigins replaces the allowed origins, the current ones are kept when the new ones are invalid
+func (m *CORSMiddleware) SetAllowOrigins(allowOrigins []string) error {
 	config := cors.Config{
-		AllowAllOrigins:  true,
+		AllowOrigins:     allowOrigins,
+		AllowWildcard:    true,
 		AllowMethods:     []string{"POST", "GET", "PUT", "DELETE", "PATCH", "HEAD"},
 		AllowHeaders:     []string{"Origin", "Content-Length", "Content-Type", "Authorization"},
 		ExposeHeaders:    []string{"Content-Length"},
 		AllowCredentials: true,
 		MaxAge:           86400 * time.Second,
 	}
-	return cors.New(config)
+	if err := config.Validate(); err != nil {
+		return err
+	}
+
+	h := cors.New(config)
+	m.handler.Store(&h)
+	return nil
+}
+
+func (m *CORSMiddleware) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		(*m.handler.Load())(c)
+	}
 }
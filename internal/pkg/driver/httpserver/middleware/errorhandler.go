@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"starter-go/internal/pkg/env"
@@ -19,6 +20,12 @@ func ErrorHandler() gin.HandlerFunc {
 		}
 
 		err := c.Errors[0].Err
+		// the request ran out of time, whatever the layer that gave up reported, see TimeoutMiddleware
+		if c.Request.Context().Err() == context.DeadlineExceeded {
+			if e, ok := err.(errors.ServiceError); !ok || e.Code() != errors.CodeTimeout {
+				err = errors.ErrTimeout(err)
+			}
+		}
 		var (
 			statusCode = http.StatusInternalServerError
 			resp       = gin.H{"message": "Internal Server Error"}
@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"starter-go/internal/pkg/flags"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Flags makes the feature flags of f available to FlagEnabled, and to flags.Enabled with the request context.
+// userID returns the id of the authenticated user, for flags rolled out by user_id, it can be nil when there's no authentication.
+// Rollouts are keyed on ids the client can't choose, the client IP (see server.trusted_proxies) or the user id, never X-Request-Id.
+func Flags(f *flags.Flags, userID func(c *gin.Context) string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var id string
+		if userID != nil {
+			id = userID(c)
+		}
+		ctx := flags.NewContext(GetContext(c), f, c.ClientIP(), id)
+		c.Set(contextKey, ctx)
+		c.Request = c.Request.WithContext(flags.WithEvaluation(c.Request.Context(), ctx))
+		c.Next()
+	}
+}
+
+// FlagEnabled reports whether the flag is on for the request, it is always off when the Flags middleware isn't used
+func FlagEnabled(c *gin.Context, name string) bool {
+	return flags.Enabled(GetContext(c), name)
+}
@@ -0,0 +1,17 @@
+package middleware
+
+import (
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+)
+
+// InFlight counts the requests being served in n, the server reports it while draining on shutdown
+func InFlight(n *atomic.Int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		n.Add(1)
+		defer n.Add(-1)
+
+		c.Next()
+	}
+}
@@ -0,0 +1,114 @@
+package middleware
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"starter-go/internal/pkg/errors"
+	"starter-go/internal/pkg/logger"
+	"starter-go/internal/pkg/ratelimit"
+)
+
+// Headers of the rate limits, see the RateLimit header fields draft of the IETF
+const (
+	responseHeaderRetryAfter         = "Retry-After"
+	responseHeaderRateLimitLimit     = "RateLimit-Limit"
+	responseHeaderRateLimitRemaining = "RateLimit-Remaining"
+	responseHeaderRateLimitReset     = "RateLimit-Reset"
+	responseHeaderRateLimitPolicy    = "RateLimit-Policy"
+)
+
+// RateLimitMiddleware limits the requests of the route groups of its policies, which can be replaced at runtime, e.g. on config reload
+type RateLimitMiddleware struct {
+	store   ratelimit.Store
+	subject func(c *gin.Context) string
+	apiKey  func(c *gin.Context, key string) (string, bool)
+	conf    atomic.Pointer[rateLimitConf]
+}
+
+type rateLimitConf struct {
+	apiKeyHeader string
+	policies     ratelimit.Policies
+}
+
+// NewRateLimit creates the rate limit middleware, the buckets are kept in store.
+// subject returns the authenticated client, for the policies keyed by subject, it can be nil when there's no authentication.
+// apiKey returns the client of the API key of the request, for the policies keyed by api_key, e.g. ratelimit.APIKeys.Lookup.
+// An unknown key is counted by IP so clients can't get fresh buckets by making keys up. When it's nil every request is counted by IP.
+func NewRateLimit(store ratelimit.Store, apiKeyHeader string, policies []ratelimit.Policy, subject func(c *gin.Context) string, apiKey func(c *gin.Context, key string) (string, bool)) *RateLimitMiddleware {
+	m := &RateLimitMiddleware{store: store, subject: subject, apiKey: apiKey}
+	m.SetPolicies(apiKeyHeader, policies)
+	return m
+}
+
+// SetPolicies replaces the policies, the buckets of the clients are kept
+func (m *RateLimitMiddleware) SetPolicies(apiKeyHeader string, policies []ratelimit.Policy) {
+	m.conf.Store(&rateLimitConf{apiKeyHeader: apiKeyHeader, policies: ratelimit.NewPolicies(policies)})
+}
+
+// Handler takes a token from the bucket of the client for the policy of the route, the RateLimit-* headers report the bucket.
+// Once it's empty, the request is rejected with 429 and Retry-After. A failing store lets the requests through.
+func (m *RateLimitMiddleware) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		conf := m.conf.Load()
+		policy, ok := conf.policies.Match(c.Request.Method, c.FullPath())
+		if !ok {
+			c.Next()
+			return
+		}
+
+		bucket := policy.Bucket()
+		result, err := m.store.Take(GetContext(c), m.key(c, conf, policy), bucket)
+		if err != nil {
+			logger.WarnCtx(GetContext(c), "[RateLimit] store failed, request not limited", "policy", policy.Path, "error", err.Error())
+			c.Next()
+			return
+		}
+
+		c.Header(responseHeaderRateLimitLimit, strconv.FormatUint(uint64(bucket.Burst), 10))
+		c.Header(responseHeaderRateLimitRemaining, strconv.FormatUint(uint64(result.Remaining), 10))
+		c.Header(responseHeaderRateLimitReset, seconds(result.ResetAfter))
+		c.Header(responseHeaderRateLimitPolicy, fmt.Sprintf("%d;w=%s;burst=%d", policy.Limit, seconds(policy.Period), bucket.Burst))
+		if !result.Allowed {
+			retryAfter := seconds(result.RetryAfter)
+			c.Header(responseHeaderRetryAfter, retryAfter)
+			c.Error(errors.ErrRateLimited(retryAfter + "s"))
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// key identifies the bucket of the client for the policy, by IP unless the valid API key or the subject the policy is keyed by is there
+func (m *RateLimitMiddleware) key(c *gin.Context, conf *rateLimitConf, policy ratelimit.Policy) string {
+	client := "ip:" + c.ClientIP()
+	switch policy.KeyBy {
+	case ratelimit.KeyByAPIKey:
+		if apiKey := c.GetHeader(conf.apiKeyHeader); apiKey != "" && m.apiKey != nil {
+			// the store never holds the key itself, only the client it belongs to
+			if name, ok := m.apiKey(c, apiKey); ok {
+				client = "api_key:" + name
+			}
+		}
+	case ratelimit.KeyBySubject:
+		if m.subject != nil {
+			if subject := m.subject(c); subject != "" {
+				client = "subject:" + subject
+			}
+		}
+	}
+	return fmt.Sprintf("%s %s|%s", strings.Join(policy.Methods, ","), policy.Path, client)
+}
+
+// seconds returns d in whole seconds, rounded up so clients don't retry too early
+func seconds(d time.Duration) string {
+	return strconv.FormatInt(int64(math.Ceil(d.Seconds())), 10)
+}
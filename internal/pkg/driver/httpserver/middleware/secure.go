@@ -10,7 +10,10 @@ func Headers() gin.HandlerFunc {
 		c.Header("X-DNS-Prefetch-Control", "off")
 		// Denies website content to be served in an iframe
 		c.Header("X-Frame-Options", "DENY")
-		c.Header("Strict-Transport-Security", "max-age=5184000; includeSubDomains")
+		// HSTS is ignored by browsers over plain HTTP, and would pin HTTPS on a host not serving it
+		if c.Request.TLS != nil {
+			c.Header("Strict-Transport-Security", "max-age=5184000; includeSubDomains")
+		}
 		// Prevents Internet Explorer from executing downloads in site's context
 		c.Header("X-Download-Options", "noopen")
 		// Minimal XSS protection
@@ -0,0 +1,114 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"starter-go/internal/pkg/errors"
+)
+
+// requestHeaderTimeout is the timeout the client waits for the response, in ms or as a Go duration (e.g. 1500ms, 2s)
+const requestHeaderTimeout = "X-Request-Timeout"
+
+// TimeoutMiddleware puts a deadline on the context of the requests, whose timeouts can be replaced at runtime, e.g. on config reload
+type TimeoutMiddleware struct {
+	timeouts atomic.Pointer[timeouts]
+}
+
+type timeouts struct {
+	def time.Duration
+	// routes are the timeouts of the route groups, the longest path first
+	routes []routeTimeout
+}
+
+type routeTimeout struct {
+	path    string
+	timeout time.Duration
+}
+
+// NewTimeout creates the timeout middleware, def is the timeout of the routes, routes the timeouts of route groups by path prefix.
+// A timeout of 0 disables it.
+func NewTimeout(def time.Duration, routes map[string]time.Duration) *TimeoutMiddleware {
+	m := &TimeoutMiddleware{}
+	m.SetTimeouts(def, routes)
+	return m
+}
+
+// SetTimeouts replaces the timeouts, see NewTimeout
+func (m *TimeoutMiddleware) SetTimeouts(def time.Duration, routes map[string]time.Duration) {
+	t := &timeouts{def: def}
+	for path, timeout := range routes {
+		t.routes = append(t.routes, routeTimeout{path: strings.TrimSuffix(path, "/"), timeout: timeout})
+	}
+	sort.Slice(t.routes, func(i, j int) bool {
+		return len(t.routes[i].path) > len(t.routes[j].path)
+	})
+	m.timeouts.Store(t)
+}
+
+// Timeout returns the timeout of the route path, e.g. /api/v1/examples/:example_id
+func (m *TimeoutMiddleware) Timeout(path string) time.Duration {
+	t := m.timeouts.Load()
+	for _, r := range t.routes {
+		if path == r.path || strings.HasPrefix(path, r.path+"/") {
+			return r.timeout
+		}
+	}
+	return t.def
+}
+
+// Handler cancels the context of the request once its timeout is over, the request context and GetContext,
+// so the services and repositories give up. The error they return is then rendered as a 504 by ErrorHandler.
+// X-Request-Timeout shortens the timeout of the route, it can't extend it.
+func (m *TimeoutMiddleware) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		timeout := m.Timeout(c.FullPath())
+		if header := c.GetHeader(requestHeaderTimeout); header != "" {
+			requested, err := parseRequestTimeout(header)
+			if err != nil {
+				c.Error(errors.ErrInvalidFieldFormat(requestHeaderTimeout, err))
+				c.Abort()
+				return
+			}
+			if timeout == 0 || requested < timeout {
+				timeout = requested
+			}
+		}
+		if timeout == 0 {
+			c.Next()
+			return
+		}
+
+		deadline := time.Now().Add(timeout)
+		reqCtx, cancelReq := context.WithDeadline(c.Request.Context(), deadline)
+		defer cancelReq()
+		ctx, cancel := context.WithDeadline(GetContext(c), deadline)
+		defer cancel()
+		c.Request = c.Request.WithContext(reqCtx)
+		c.Set(contextKey, ctx)
+
+		c.Next()
+	}
+}
+
+// parseRequestTimeout parses X-Request-Timeout, in ms or as a Go duration
+func parseRequestTimeout(value string) (time.Duration, error) {
+	d, err := time.ParseDuration(value)
+	if ms, msErr := strconv.ParseUint(value, 10, 32); msErr == nil {
+		d, err = time.Duration(ms)*time.Millisecond, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("must be positive, got %s", value)
+	}
+	return d, nil
+}
@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"starter-go/internal/pkg/errors"
+)
+
+// BearerToken rejects the requests without the "Authorization: Bearer <token>" header with 401.
+// token is read on every request so a token changed on config reload applies right away, an empty token rejects every request.
+func BearerToken(token func() string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		want := token()
+		got, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok || want == "" || subtle.ConstantTimeCompare([]byte(got), []byte(want)) != 1 {
+			c.Header("WWW-Authenticate", "Bearer")
+			c.Error(errors.ErrUnauthorized("missing or invalid token"))
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
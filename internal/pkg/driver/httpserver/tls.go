@@ -0,0 +1,130 @@
+package httpserver
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"starter-go/internal/pkg/config"
+	"starter-go/internal/pkg/logger"
+)
+
+// certReloader serves the certificate and the client CAs read from disk, they're reloaded on a handshake when their files changed,
+// so a renewed certificate applies without restart. Files that fail to load keep the previous ones in use.
+type certReloader struct {
+	conf     config.TLSConfig
+	interval time.Duration
+
+	mu sync.Mutex
+	// current is the config of the handshakes, rebuilt on reload
+	current *tls.Config
+	// modTimes are the modification times of the files current was loaded from
+	modTimes []time.Time
+	checked  time.Time
+}
+
+// newCertReloader loads the files of conf, an invalid certificate or CA fails the start of the server
+func newCertReloader(conf config.TLSConfig) (*certReloader, error) {
+	r := &certReloader{
+		conf:     conf,
+		interval: time.Duration(conf.GetReloadInterval()) * time.Millisecond,
+	}
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// TLSConfig returns the config of the server, the certificates are provided per handshake by GetConfigForClient
+func (r *certReloader) TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion:         r.conf.GetMinVersion(),
+		CipherSuites:       r.conf.GetCipherSuites(),
+		NextProtos:         []string{"h2", "http/1.1"},
+		GetConfigForClient: r.configForClient,
+	}
+}
+
+func (r *certReloader) configForClient(*tls.ClientHelloInfo) (*tls.Config, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if time.Since(r.checked) >= r.interval {
+		r.checked = time.Now()
+		if r.changed() {
+			if err := r.reloadLocked(); err != nil {
+				logger.Error("[TLS] failed to reload certificates, keeping the current ones", "error", err.Error())
+			} else {
+				logger.Info("[TLS] certificates reloaded", "cert_file", r.conf.GetCertFile())
+			}
+		}
+	}
+	return r.current, nil
+}
+
+// files returns the files the config is loaded from, the client CA is optional
+func (r *certReloader) files() []string {
+	files := []string{r.conf.GetCertFile(), r.conf.GetKeyFile()}
+	if r.conf.GetClientCA() != "" {
+		files = append(files, r.conf.GetClientCA())
+	}
+	return files
+}
+
+// changed reports whether a file was modified since it was loaded, a missing file is reported by the reload
+func (r *certReloader) changed() bool {
+	for i, f := range r.files() {
+		info, err := os.Stat(f)
+		if err != nil || !info.ModTime().Equal(r.modTimes[i]) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *certReloader) reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.reloadLocked()
+}
+
+func (r *certReloader) reloadLocked() error {
+	// stat before reading, a file written in between is reloaded on the next check
+	modTimes := make([]time.Time, 0, 3)
+	for _, f := range r.files() {
+		info, err := os.Stat(f)
+		if err != nil {
+			return err
+		}
+		modTimes = append(modTimes, info.ModTime())
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.conf.GetCertFile(), r.conf.GetKeyFile())
+	if err != nil {
+		return fmt.Errorf("failed to load certificate %s: %w", r.conf.GetCertFile(), err)
+	}
+
+	cfg := r.TLSConfig()
+	cfg.GetConfigForClient = nil
+	cfg.Certificates = []tls.Certificate{cert}
+	cfg.ClientAuth = r.conf.GetClientAuth()
+	if ca := r.conf.GetClientCA(); ca != "" {
+		pem, err := os.ReadFile(ca)
+		if err != nil {
+			return fmt.Errorf("failed to read client CA %s: %w", ca, err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return errors.New("no certificate found in client CA " + ca)
+		}
+		cfg.ClientCAs = pool
+	}
+
+	r.current = cfg
+	r.modTimes = modTimes
+	return nil
+}
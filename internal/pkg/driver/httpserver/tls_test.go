@@ -0,0 +1,222 @@
+package httpserver
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"starter-go/internal/pkg/clientcert"
+	"starter-go/internal/pkg/config"
+	mw "starter-go/internal/pkg/driver/httpserver/middleware"
+)
+
+type testTLSConfig struct {
+	config.TLSConfig
+	enabled    bool
+	dir        string
+	clientAuth tls.ClientAuthType
+}
+
+func (c testTLSConfig) GetEnabled() bool                  { return c.enabled }
+func (c testTLSConfig) GetCertFile() string               { return filepath.Join(c.dir, "server.pem") }
+func (c testTLSConfig) GetKeyFile() string                { return filepath.Join(c.dir, "server-key.pem") }
+func (c testTLSConfig) GetMinVersion() uint16             { return tls.VersionTLS12 }
+func (c testTLSConfig) GetCipherSuites() []uint16         { return nil }
+func (c testTLSConfig) GetClientAuth() tls.ClientAuthType { return c.clientAuth }
+func (c testTLSConfig) GetClientCA() string               { return filepath.Join(c.dir, "ca.pem") }
+func (c testTLSConfig) GetReloadInterval() uint           { return 0 }
+
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+// issue creates a certificate signed by parent, self-signed when parent is nil
+func issue(t *testing.T, parent *testCert, serial int64, tmpl x509.Certificate) *testCert {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl.SerialNumber = big.NewInt(serial)
+	tmpl.NotBefore = time.Now().Add(-time.Hour)
+	tmpl.NotAfter = time.Now().Add(time.Hour)
+
+	signer, signerKey := &tmpl, key
+	if parent != nil {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &tmpl, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCert{cert: cert, key: key}
+}
+
+func (c *testCert) write(t *testing.T, certPath, keyPath string) {
+	t.Helper()
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.cert.Raw})
+	if err := os.WriteFile(certPath, certPEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if keyPath == "" {
+		return
+	}
+	der, err := x509.MarshalECPrivateKey(c.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func (c *testCert) tlsCertificate() tls.Certificate {
+	return tls.Certificate{Certificate: [][]byte{c.cert.Raw}, PrivateKey: c.key}
+}
+
+func TestServeMutualTLS(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	dir := t.TempDir()
+
+	ca := issue(t, nil, 1, x509.Certificate{
+		Subject:               pkix.Name{CommonName: "test CA"},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	})
+	serverTmpl := x509.Certificate{
+		Subject:     pkix.Name{CommonName: "localhost"},
+		IPAddresses: []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	spiffe, _ := url.Parse("spiffe://example.org/billing")
+	client := issue(t, ca, 3, x509.Certificate{
+		Subject:     pkix.Name{CommonName: "billing"},
+		URIs:        []*url.URL{spiffe},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+
+	conf := testTLSConfig{enabled: true, dir: dir, clientAuth: tls.VerifyClientCertIfGiven}
+	ca.write(t, conf.GetClientCA(), "")
+	issue(t, ca, 2, serverTmpl).write(t, conf.GetCertFile(), conf.GetKeyFile())
+
+	srv := NewServer(testServerConfig{port: 18096, grace: 100, tls: conf}, testCORSConfig{})
+	srv.Engine().GET("/whoami", func(c *gin.Context) {
+		id, _ := clientcert.FromContext(mw.GetContext(c))
+		c.JSON(http.StatusOK, gin.H{"client": id.Name()})
+	})
+	var id clientcert.Identity
+	srv.Engine().GET("/identity", func(c *gin.Context) {
+		id, _ = clientcert.FromContext(c.Request.Context())
+	})
+	srv.Engine().GET("/private", mw.RequireClientCert(), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	if err := srv.Start(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer srv.Stop(context.Background())
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	// a new client per request, so every request makes a handshake.
+	// The certificate is always sent, the go client otherwise skips the ones not issued by the CAs the server asks for.
+	get := func(path string, certs ...tls.Certificate) (*http.Response, error) {
+		tlsConf := &tls.Config{RootCAs: roots}
+		if len(certs) > 0 {
+			tlsConf.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+				return &certs[0], nil
+			}
+		}
+		c := &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConf}}
+		return c.Get("https://127.0.0.1:18096" + path)
+	}
+
+	resp, err := get("/whoami", client.tlsCertificate())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp.Body.Close()
+	if got := resp.Header.Get("Strict-Transport-Security"); got == "" {
+		t.Error("expected HSTS over TLS")
+	}
+	if serial := resp.TLS.PeerCertificates[0].SerialNumber.Int64(); serial != 2 {
+		t.Errorf("expected the server certificate 2, got %d", serial)
+	}
+
+	if resp, err = get("/identity", client.tlsCertificate()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp.Body.Close()
+	if id.Name() != "spiffe://example.org/billing" || id.CommonName != "billing" || id.SerialNumber != "3" {
+		t.Errorf("expected the identity of the client certificate, got %+v", id)
+	}
+
+	// client certificates are optional, except on the routes requiring one
+	if resp, err = get("/private"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected 401 without client certificate, got %d", resp.StatusCode)
+	}
+
+	// a certificate from another CA is rejected on the handshake
+	other := issue(t, nil, 4, x509.Certificate{Subject: pkix.Name{CommonName: "intruder"}, ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}})
+	if _, err = get("/whoami", other.tlsCertificate()); err == nil {
+		t.Error("expected a certificate of an unknown CA to be rejected")
+	}
+
+	// the renewed certificate is served on the next handshake
+	issue(t, ca, 5, serverTmpl).write(t, conf.GetCertFile(), conf.GetKeyFile())
+	later := time.Now().Add(time.Minute)
+	_ = os.Chtimes(conf.GetCertFile(), later, later)
+	if resp, err = get("/whoami"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp.Body.Close()
+	if serial := resp.TLS.PeerCertificates[0].SerialNumber.Int64(); serial != 5 {
+		t.Errorf("expected the renewed certificate 5, got %d", serial)
+	}
+
+	// an invalid certificate keeps the current one
+	_ = os.WriteFile(conf.GetCertFile(), []byte("not a certificate"), 0o600)
+	later = later.Add(time.Minute)
+	_ = os.Chtimes(conf.GetCertFile(), later, later)
+	if resp, err = get("/whoami"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp.Body.Close()
+	if serial := resp.TLS.PeerCertificates[0].SerialNumber.Int64(); serial != 5 {
+		t.Errorf("expected the current certificate 5 to be kept, got %d", serial)
+	}
+}
+
+func TestServeTLSInvalidCertificate(t *testing.T) {
+	conf := testTLSConfig{enabled: true, dir: t.TempDir()}
+	srv := NewServer(testServerConfig{port: 18096, tls: conf}, testCORSConfig{})
+	if err := srv.Start(context.Background()); err == nil {
+		srv.Stop(context.Background())
+		t.Fatal("expected the start to fail without certificate")
+	}
+}
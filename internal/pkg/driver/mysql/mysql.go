@@ -3,6 +3,8 @@ package mysql
 import (
 	"fmt"
 	"log"
+	"net/url"
+	"starter-go/internal/pkg/clock"
 	"starter-go/internal/pkg/config"
 	"time"
 
@@ -10,23 +12,15 @@ import (
 	"gorm.io/gorm"
 )
 
-func NewDatabase() *gorm.DB {
-	conf := config.Database()
-	// user:password@tcp(host:port)/dbname?charset=utf8mb4&parseTime=True&loc=Local
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=utf8mb4&parseTime=True&loc=Local",
-		conf.GetUser(),
-		conf.GetPassword(),
-		conf.GetHost(),
-		conf.GetPort(),
-		conf.GetName(),
-	)
-
+// NewDatabase connects to MySQL, times are read in the location of clk and gorm timestamps come from clk.
+// The connection is retried at startup, the process exits when the database stays unreachable.
+func NewDatabase(conf config.DatabaseConfig, clk clock.Clock) *gorm.DB {
 	var db *gorm.DB
 	var err error
 
 	// retry database connection at startup
 	for i := 0; i < 3; i++ {
-		db, err = gorm.Open(mysql.Open(dsn), &gorm.Config{})
+		db, err = Open(conf, clk)
 		if err == nil {
 			break
 		}
@@ -40,3 +34,18 @@ func NewDatabase() *gorm.DB {
 
 	return db
 }
+
+// Open connects to MySQL once, the connection is checked with a ping
+func Open(conf config.DatabaseConfig, clk clock.Clock) (*gorm.DB, error) {
+	// user:password@tcp(host:port)/dbname?charset=utf8mb4&parseTime=True&loc=Asia%2FJakarta
+	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=utf8mb4&parseTime=True&loc=%s",
+		conf.GetUser(),
+		conf.GetPassword(),
+		conf.GetHost(),
+		conf.GetPort(),
+		conf.GetName(),
+		url.QueryEscape(clk.Location().String()),
+	)
+
+	return gorm.Open(mysql.Open(dsn), &gorm.Config{NowFunc: clk.Now})
+}
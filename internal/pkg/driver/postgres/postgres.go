@@ -0,0 +1,58 @@
+package postgres
+
+import (
+	"fmt"
+	"log"
+	"starter-go/internal/pkg/clock"
+	"starter-go/internal/pkg/config"
+	"strings"
+	"time"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// NewDatabase connects to PostgreSQL, the session time zone is the location of clk and gorm timestamps come from clk.
+// The connection is retried at startup, the process exits when the database stays unreachable.
+func NewDatabase(conf config.DatabaseConfig, clk clock.Clock) *gorm.DB {
+	var db *gorm.DB
+	var err error
+
+	// retry database connection at startup
+	for i := 0; i < 3; i++ {
+		db, err = Open(conf, clk)
+		if err == nil {
+			break
+		}
+		log.Printf("Failed to connect to database (attempt %d/3): %v. Retrying in 5 seconds...", i+1, err)
+		time.Sleep(5 * time.Second)
+	}
+
+	if err != nil {
+		log.Fatalf("Failed to connect to database after 3 attempts: %v", err)
+	}
+
+	return db
+}
+
+// Open connects to PostgreSQL once, the connection is checked with a ping
+func Open(conf config.DatabaseConfig, clk clock.Clock) (*gorm.DB, error) {
+	// host=localhost port=5432 user=postgres password=secret dbname=app sslmode=disable TimeZone=Asia/Jakarta
+	dsn := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s TimeZone=%s",
+		quote(conf.GetHost()),
+		conf.GetPort(),
+		quote(conf.GetUser()),
+		quote(conf.GetPassword()),
+		quote(conf.GetName()),
+		quote(conf.GetSSLMode()),
+		quote(clk.Location().String()),
+	)
+
+	return gorm.Open(postgres.Open(dsn), &gorm.Config{NowFunc: clk.Now})
+}
+
+// quote escapes a value of a keyword/value connection string, so passwords can contain spaces and quotes
+func quote(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	return "'" + strings.ReplaceAll(value, "'", `\'`) + "'"
+}
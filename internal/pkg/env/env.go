@@ -1,11 +1,13 @@
 package env
 
-import "os"
+import "starter-go/internal/pkg/config"
 
+// IsDevelopment reports whether the service runs in development, as set by server.env (APP_SERVER_ENV)
 func IsDevelopment() bool {
-	return os.Getenv("APP_ENV") == "development"
+	return config.CurrentEnvironment().IsDevelopment()
 }
 
+// IsProduction reports whether server.env (APP_SERVER_ENV) is production
 func IsProduction() bool {
-	return os.Getenv("APP_ENV") == "production"
+	return config.CurrentEnvironment().IsProduction()
 }
@@ -64,3 +64,15 @@ func ErrDuplicateRequest(reason string) ServiceError {
 func ErrNotFound(entity string, err error) ServiceError {
 	return New(CodeNotFound, fmt.Sprintf("%s not found", entity), err)
 }
+
+func ErrUnauthorized(reason string) ServiceError {
+	return New(CodeUnauthorized, fmt.Sprintf("Unauthorized: %s", reason), nil)
+}
+
+func ErrTimeout(err error) ServiceError {
+	return New(CodeTimeout, "Request timed out", err)
+}
+
+func ErrRateLimited(retryAfter string) ServiceError {
+	return New(CodeRateLimited, fmt.Sprintf("Too many requests, retry after %s", retryAfter), nil)
+}
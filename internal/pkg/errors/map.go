@@ -8,6 +8,9 @@ const (
 	CodeInvalidFormat    = "INVALID_FORMAT"
 	CodeDuplicateRequest = "DUPLICATE_REQUEST"
 	CodeNotFound         = "NOT_FOUND"
+	CodeUnauthorized     = "UNAUTHORIZED"
+	CodeTimeout          = "TIMEOUT"
+	CodeRateLimited      = "RATE_LIMITED"
 	CodeInternal         = "INTERNAL_ERROR"
 )
 
@@ -20,6 +23,12 @@ func HTTPStatusFromCode(code string) int {
 		return http.StatusBadRequest
 	case CodeNotFound:
 		return http.StatusNotFound
+	case CodeUnauthorized:
+		return http.StatusUnauthorized
+	case CodeTimeout:
+		return http.StatusGatewayTimeout
+	case CodeRateLimited:
+		return http.StatusTooManyRequests
 	default:
 		return http.StatusInternalServerError
 	}
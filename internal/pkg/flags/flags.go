@@ -0,0 +1,140 @@
+package flags
+
+import (
+	"context"
+	"hash/fnv"
+	"maps"
+	"slices"
+	"sync"
+	"sync/atomic"
+
+	"starter-go/internal/pkg/logger"
+	"starter-go/internal/pkg/logger/ctxfield"
+)
+
+// Ids a rollout can be keyed on, both are derived by the server so a client can't pick its own flag state
+const (
+	RolloutByClientIP = "client_ip"
+	RolloutByUserID   = "user_id"
+)
+
+// Flag is a feature flag, read from the flags config section
+type Flag struct {
+	Name string
+	// Enabled is the kill switch, a disabled flag is off for everyone, including the allow-list
+	Enabled bool
+	// Rollout is the percentage of ids the flag is on for, 100 turns it on for everyone
+	Rollout uint
+	// RolloutBy is the id the rollout is keyed on, RolloutByClientIP or RolloutByUserID
+	RolloutBy string
+	// Allow lists the ids the flag is always on for
+	Allow []string
+}
+
+// Flags evaluates feature flags, Update swaps the flags atomically so it can be called on config reload
+type Flags struct {
+	flags atomic.Pointer[map[string]Flag]
+}
+
+func New(flags []Flag) *Flags {
+	f := &Flags{}
+	f.Update(flags)
+	return f
+}
+
+// Update replaces every flag, flags missing from the list are off
+func (f *Flags) Update(flags []Flag) {
+	m := make(map[string]Flag, len(flags))
+	for _, flag := range flags {
+		m[flag.Name] = flag
+	}
+	f.flags.Store(&m)
+}
+
+// Evaluate reports whether the flag is on for the given client IP and user id (empty when anonymous).
+// Rollouts are deterministic, the same id always gets the same result as long as the rollout isn't lowered.
+func (f *Flags) Evaluate(name, clientIP, userID string) bool {
+	flag, ok := (*f.flags.Load())[name]
+	if !ok || !flag.Enabled {
+		return false
+	}
+
+	id := clientIP
+	if flag.RolloutBy == RolloutByUserID {
+		id = userID
+	}
+
+	if id != "" && slices.Contains(flag.Allow, id) {
+		return true
+	}
+	if flag.Rollout >= 100 {
+		return true
+	}
+	if id == "" {
+		return false
+	}
+	return bucket(name, id) < uint32(flag.Rollout)
+}
+
+// bucket spreads ids over 0-99, the flag name is part of the hash so rollouts of different flags are independent
+func bucket(name, id string) uint32 {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(name + "/" + id))
+	return h.Sum32() % 100
+}
+
+type key string
+
+const (
+	ctxEvaluation key = "flags_evaluation"
+)
+
+// evaluation holds the flags evaluated during a request, a flag is evaluated once so a request sees a consistent value across reloads
+type evaluation struct {
+	flags    *Flags
+	clientIP string
+	userID   string
+	// logCtx carries the log fields of the request, the evaluations are recorded there whichever context Enabled is called with
+	logCtx context.Context
+
+	mu      sync.Mutex
+	results map[string]bool
+}
+
+// NewContext returns a context where Enabled evaluates f, for the given client IP and user id (empty when anonymous)
+func NewContext(ctx context.Context, f *Flags, clientIP, userID string) context.Context {
+	e := &evaluation{flags: f, clientIP: clientIP, userID: userID, logCtx: ctx, results: map[string]bool{}}
+	return context.WithValue(ctx, ctxEvaluation, e)
+}
+
+// WithEvaluation returns ctx sharing the evaluation of from, created by NewContext,
+// so a flag has the same value whichever of the two contexts it's evaluated with
+func WithEvaluation(ctx, from context.Context) context.Context {
+	e, ok := from.Value(ctxEvaluation).(*evaluation)
+	if !ok {
+		return ctx
+	}
+	return context.WithValue(ctx, ctxEvaluation, e)
+}
+
+// Enabled reports whether the flag is on for the request of ctx, it is off when ctx wasn't created by NewContext.
+// Evaluations are recorded in the "flags" field of the *Ctx logs of the request.
+func Enabled(ctx context.Context, name string) bool {
+	e, ok := ctx.Value(ctxEvaluation).(*evaluation)
+	if !ok {
+		return false
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if result, ok := e.results[name]; ok {
+		return result
+	}
+
+	result := e.flags.Evaluate(name, e.clientIP, e.userID)
+	e.results[name] = result
+	ctxfield.Set(e.logCtx, "flags", maps.Clone(e.results))
+	logger.DebugCtx(e.logCtx, "[Flags] evaluated", "flag", name, "enabled", result)
+	return result
+}
@@ -0,0 +1,104 @@
+package flags
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"starter-go/internal/pkg/logger/ctxfield"
+)
+
+func TestEvaluate(t *testing.T) {
+	f := New([]Flag{
+		{Name: "off", Enabled: false, Rollout: 100, Allow: []string{"10.0.0.1"}},
+		{Name: "on", Enabled: true, Rollout: 100},
+		{Name: "allowed", Enabled: true, Rollout: 0, RolloutBy: RolloutByUserID, Allow: []string{"user-1"}},
+		{Name: "half", Enabled: true, Rollout: 50},
+	})
+
+	tests := []struct {
+		name     string
+		clientIP string
+		userID   string
+		expected bool
+	}{
+		{"off", "10.0.0.1", "", false},
+		{"on", "", "", true},
+		{"allowed", "10.0.0.1", "user-1", true},
+		{"allowed", "user-1", "user-2", false},
+		{"unknown", "10.0.0.1", "user-1", false},
+		{"half", "", "", false},
+	}
+	for _, tt := range tests {
+		if got := f.Evaluate(tt.name, tt.clientIP, tt.userID); got != tt.expected {
+			t.Errorf("%s(%q, %q): expected %v, got %v", tt.name, tt.clientIP, tt.userID, tt.expected, got)
+		}
+	}
+
+	on := 0
+	for i := 0; i < 1000; i++ {
+		id := fmt.Sprintf("10.0.%d.%d", i/256, i%256)
+		result := f.Evaluate("half", id, "")
+		if result != f.Evaluate("half", id, "") {
+			t.Fatalf("expected rollout to be deterministic for %s", id)
+		}
+		if result {
+			on++
+		}
+	}
+	if on < 400 || on > 600 {
+		t.Errorf("expected about half of the ids in the rollout, got %d/1000", on)
+	}
+}
+
+func TestEnabledIsConsistentWithinRequest(t *testing.T) {
+	f := New([]Flag{{Name: "new_create", Enabled: true, Rollout: 100}})
+	ctx := NewContext(ctxfield.New(context.Background()), f, "10.0.0.1", "")
+
+	if !Enabled(ctx, "new_create") {
+		t.Fatal("expected flag to be on")
+	}
+
+	// a reload during the request doesn't change its evaluation
+	f.Update(nil)
+	if !Enabled(ctx, "new_create") {
+		t.Error("expected flag to stay on for the current request")
+	}
+	if f.Evaluate("new_create", "", "") {
+		t.Error("expected flag to be off after update")
+	}
+
+	kv := ctxfield.Values(ctx)
+	if len(kv) != 2 || kv[0] != "flags" {
+		t.Fatalf("expected evaluations in the log fields, got %v", kv)
+	}
+	if evaluated := kv[1].(map[string]bool); !evaluated["new_create"] {
+		t.Errorf("expected new_create to be recorded as on, got %v", evaluated)
+	}
+
+	if Enabled(context.Background(), "new_create") {
+		t.Error("expected flag to be off without an evaluation context")
+	}
+}
+
+func TestWithEvaluationSharesResults(t *testing.T) {
+	f := New([]Flag{{Name: "new_create", Enabled: true, Rollout: 100}})
+	ctx := NewContext(ctxfield.New(context.Background()), f, "10.0.0.1", "")
+	// e.g. the request context passed down to the services
+	other := WithEvaluation(context.Background(), ctx)
+
+	if !Enabled(other, "new_create") {
+		t.Fatal("expected flag to be on from the shared context")
+	}
+	f.Update(nil)
+	if !Enabled(ctx, "new_create") {
+		t.Error("expected the evaluation of the shared context to be reused")
+	}
+	if kv := ctxfield.Values(ctx); len(kv) != 2 || kv[0] != "flags" {
+		t.Errorf("expected the evaluation in the log fields of the request, got %v", kv)
+	}
+
+	if WithEvaluation(context.Background(), context.Background()).Value(ctxEvaluation) != nil {
+		t.Error("expected no evaluation to be shared from a context without one")
+	}
+}
@@ -0,0 +1,225 @@
+// Package gen generates the files of a new domain from templates following the layering of the example domain:
+// entity and interfaces, repositories, service, REST handler, module and tests.
+package gen
+
+import (
+	"bufio"
+	"bytes"
+	"embed"
+	"fmt"
+	"go/format"
+	"go/token"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+//go:embed templates/*.tmpl
+var templates embed.FS
+
+// FieldTypes are the types a field can have, see ParseFields
+var FieldTypes = []string{"string", "int", "int64", "float64", "bool", "time"}
+
+// Field is a field of the domain entity, besides the ID and CreatedAt every entity has
+type Field struct {
+	// Name is the snake_case name, used for the JSON field and the column
+	Name string
+	// Type is one of FieldTypes
+	Type string
+}
+
+// Domain is the domain to generate
+type Domain struct {
+	// Name is the snake_case name of the domain, e.g. order_item
+	Name   string
+	Fields []Field
+	// Module is the Go module path, imports of the generated files start with it
+	Module string
+}
+
+var identifier = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// names the generated code uses for its own variables, a domain can't be named after them
+var reservedNames = toSet(
+	"ctx", "id", "err", "e", "s", "r", "h", "m", "c", "g", "w", "tt", "req", "body", "result", "model", "found", "all", "now", "saved",
+	"mock", "repo", "service", "handler", "domain", "repository", "rest", "entity", "module",
+	"clock", "errors", "context", "time", "storage", "gin", "gorm", "http", "json", "bytes", "fmt", "sync", "strconv", "testing", "assert",
+)
+
+func toSet(names ...string) map[string]bool {
+	set := make(map[string]bool, len(names))
+	for _, name := range names {
+		set[name] = true
+	}
+	return set
+}
+
+// ParseFields parses a comma separated list of name:type, e.g. name:string,amount:int
+func ParseFields(s string) ([]Field, error) {
+	var fields []Field
+	seen := map[string]bool{}
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		name, typ, ok := strings.Cut(part, ":")
+		if !ok {
+			return nil, fmt.Errorf("field %q: expected name:type", part)
+		}
+		if !identifier.MatchString(name) {
+			return nil, fmt.Errorf("field %q: name must be snake_case", name)
+		}
+		if name == "id" || name == "created_at" {
+			return nil, fmt.Errorf("field %q: every entity already has it", name)
+		}
+		if !validType(typ) {
+			return nil, fmt.Errorf("field %q: unknown type %q, expected one of %s", name, typ, strings.Join(FieldTypes, ", "))
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("field %q: duplicated", name)
+		}
+		seen[name] = true
+		fields = append(fields, Field{Name: name, Type: typ})
+	}
+
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("at least one field is needed")
+	}
+	return fields, nil
+}
+
+func validType(typ string) bool {
+	for _, t := range FieldTypes {
+		if t == typ {
+			return true
+		}
+	}
+	return false
+}
+
+// ModulePath reads the module path from the go.mod of root
+func ModulePath(root string) (string, error) {
+	f, err := os.Open(filepath.Join(root, "go.mod"))
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if path, ok := strings.CutPrefix(strings.TrimSpace(scanner.Text()), "module "); ok {
+			return strings.Trim(strings.TrimSpace(path), `"`), nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	return "", fmt.Errorf("no module path in %s", f.Name())
+}
+
+// Validate checks the name of the domain, the fields are checked by ParseFields
+func (d Domain) Validate() error {
+	if !identifier.MatchString(d.Name) || strings.HasSuffix(d.Name, "_") || strings.Contains(d.Name, "__") {
+		return fmt.Errorf("domain %q: name must be snake_case, e.g. order_item", d.Name)
+	}
+	if token.IsKeyword(d.Package()) || reservedNames[d.Package()] || reservedNames[d.Var()] {
+		return fmt.Errorf("domain %q: name is reserved", d.Name)
+	}
+	if len(d.Fields) == 0 {
+		return fmt.Errorf("domain %q: at least one field is needed", d.Name)
+	}
+	if d.Module == "" {
+		return fmt.Errorf("domain %q: module path is empty", d.Name)
+	}
+	return nil
+}
+
+// file is a generated file, path is relative to the root of the module
+type file struct {
+	tmpl string
+	path string
+}
+
+func (d Domain) files() []file {
+	pkg := d.Package()
+	return []file{
+		{"domain_entity.go.tmpl", filepath.Join("internal/domain", pkg, pkg+".go")},
+		{"domain_repository.go.tmpl", filepath.Join("internal/domain", pkg, "repository.go")},
+		{"domain_service.go.tmpl", filepath.Join("internal/domain", pkg, "service.go")},
+		{"repository_factory.go.tmpl", filepath.Join("internal/repository", pkg, "factory.go")},
+		{"repository_memory.go.tmpl", filepath.Join("internal/repository", pkg, "memory_repository.go")},
+		{"repository_model.go.tmpl", filepath.Join("internal/repository", pkg, "model.go")},
+		{"repository_mysql.go.tmpl", filepath.Join("internal/repository", pkg, "mysql_repository.go")},
+		{"service.go.tmpl", filepath.Join("internal/service", pkg, pkg+"_service.go")},
+		{"rest_dto.go.tmpl", filepath.Join("api/rest", pkg, "dto.go")},
+		{"rest_handler.go.tmpl", filepath.Join("api/rest", pkg, "handler.go")},
+		{"rest_routes.go.tmpl", filepath.Join("api/rest", pkg, "routes.go")},
+		{"module.go.tmpl", filepath.Join("internal/module", pkg, "module.go")},
+		{"test_handler.go.tmpl", filepath.Join("test", pkg, "handler_test.go")},
+		{"test_repository.go.tmpl", filepath.Join("test", pkg, "repository_test.go")},
+		{"test_service.go.tmpl", filepath.Join("test", pkg, "service_test.go")},
+	}
+}
+
+// Render renders the files of the domain, keyed on their path relative to the root of the module
+func (d Domain) Render() (map[string][]byte, error) {
+	if err := d.Validate(); err != nil {
+		return nil, err
+	}
+
+	tmpl, err := template.New("").ParseFS(templates, "templates/*.tmpl")
+	if err != nil {
+		return nil, err
+	}
+
+	out := map[string][]byte{}
+	for _, f := range d.files() {
+		var buf bytes.Buffer
+		if err := tmpl.ExecuteTemplate(&buf, f.tmpl, d); err != nil {
+			return nil, fmt.Errorf("%s: %w", f.tmpl, err)
+		}
+		src, err := format.Source(buf.Bytes())
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", f.tmpl, err)
+		}
+		out[f.path] = src
+	}
+	return out, nil
+}
+
+// Write renders the files of the domain under root and returns their paths.
+// Nothing is written when one of the files exists, unless force is set.
+func (d Domain) Write(root string, force bool) ([]string, error) {
+	files, err := d.Render()
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	for _, f := range d.files() {
+		paths = append(paths, f.path)
+	}
+
+	if !force {
+		for _, path := range paths {
+			if _, err := os.Stat(filepath.Join(root, path)); err == nil {
+				return nil, fmt.Errorf("%s already exists, use --force to overwrite", path)
+			}
+		}
+	}
+
+	for _, path := range paths {
+		full := filepath.Join(root, path)
+		if err := os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(full, files[path], 0o644); err != nil {
+			return nil, err
+		}
+	}
+	return paths, nil
+}
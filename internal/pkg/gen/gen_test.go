@@ -0,0 +1,164 @@
+package gen
+
+import (
+	"io/fs"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseFields(t *testing.T) {
+	fields, err := ParseFields("name:string, amount:int,due_at:time")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []Field{{"name", "string"}, {"amount", "int"}, {"due_at", "time"}}
+	if len(fields) != len(want) {
+		t.Fatalf("expected %v, got %v", want, fields)
+	}
+	for i := range want {
+		if fields[i] != want[i] {
+			t.Errorf("expected %v, got %v", want[i], fields[i])
+		}
+	}
+
+	for _, bad := range []string{"", "name", "name:uint8", "Name:string", "id:int", "name:string,name:int"} {
+		if _, err := ParseFields(bad); err == nil {
+			t.Errorf("expected %q to fail", bad)
+		}
+	}
+}
+
+func TestNames(t *testing.T) {
+	d := Domain{Name: "order_item"}
+	got := []string{d.Package(), d.Type(), d.Var(), d.Plural(), d.Table(), d.Route(), d.Code()}
+	want := []string{"orderitem", "OrderItem", "orderItem", "OrderItems", "order_items", "order-items", "ORDER_ITEM"}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("expected %q, got %q", want[i], got[i])
+		}
+	}
+
+	if p := (Domain{Name: "category"}).Plural(); p != "Categories" {
+		t.Errorf("expected Categories, got %q", p)
+	}
+	if n := (Field{Name: "user_id"}).GoName(); n != "UserID" {
+		t.Errorf("expected UserID, got %q", n)
+	}
+	if p := (Field{Name: "type"}).Param(); p != "typeValue" {
+		t.Errorf("expected typeValue, got %q", p)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	root := t.TempDir()
+	d := Domain{Name: "order", Fields: []Field{{"name", "string"}, {"due_at", "time"}}, Module: "starter-go"}
+
+	paths, err := d.Write(root, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(paths) != len(d.files()) {
+		t.Fatalf("expected %d files, got %d", len(d.files()), len(paths))
+	}
+
+	src, err := os.ReadFile(filepath.Join(root, "internal/service/order/order_service.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`"starter-go/internal/domain/order"`, "CreateOrder(ctx context.Context, name string, dueAt time.Time)"} {
+		if !strings.Contains(string(src), want) {
+			t.Errorf("expected the service to contain %s", want)
+		}
+	}
+
+	// existing files are kept unless forced
+	if _, err := d.Write(root, false); err == nil {
+		t.Error("expected existing files to fail")
+	}
+	if _, err := d.Write(root, true); err != nil {
+		t.Errorf("unexpected error with force: %v", err)
+	}
+
+	for _, name := range []string{"service", "type", "Order", "order__item"} {
+		if err := (Domain{Name: name, Fields: d.Fields, Module: "starter-go"}).Validate(); err == nil {
+			t.Errorf("expected domain %q to fail", name)
+		}
+	}
+}
+
+// TestGeneratedDomainBuilds generates a domain into a copy of this module, then vets it and runs its generated tests
+func TestGeneratedDomainBuilds(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds and tests the generated domain")
+	}
+	goBin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go is not in PATH")
+	}
+
+	root := copyModule(t, "../../..")
+	module, err := ModulePath(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := Domain{
+		Name:   "order_item",
+		Fields: []Field{{"name", "string"}, {"count", "int"}, {"total", "int64"}, {"price", "float64"}, {"active", "bool"}, {"due_at", "time"}},
+		Module: module,
+	}
+	paths, err := d.Write(root, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pkgs := map[string]bool{}
+	for _, path := range paths {
+		pkgs["./"+filepath.ToSlash(filepath.Dir(path))] = true
+	}
+	args := slices.Sorted(maps.Keys(pkgs))
+	for _, command := range []string{"vet", "test"} {
+		cmd := exec.Command(goBin, append([]string{command}, args...)...)
+		cmd.Dir = root
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Errorf("go %s of the generated domain failed: %v\n%s", command, err, out)
+		}
+	}
+}
+
+// copyModule copies the module at dir, without its git history, to a temp dir and returns it
+func copyModule(t *testing.T, dir string) string {
+	t.Helper()
+	root := t.TempDir()
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if entry.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return os.MkdirAll(filepath.Join(root, rel), 0o755)
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(filepath.Join(root, rel), b, 0o644)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return root
+}
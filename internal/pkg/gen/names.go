@@ -0,0 +1,154 @@
+package gen
+
+import (
+	"go/token"
+	"strings"
+)
+
+// initialisms are upper-cased in Go names, e.g. user_id is UserID
+var initialisms = map[string]bool{"id": true, "url": true, "uri": true, "ip": true, "api": true, "http": true, "json": true, "uuid": true, "sku": true}
+
+// camel joins the parts of a snake_case name, upper-casing the first letter of every part but the first unless upper is set
+func camel(name string, upper bool) string {
+	var b strings.Builder
+	for i, part := range strings.Split(name, "_") {
+		if part == "" {
+			continue
+		}
+		switch {
+		case i == 0 && !upper:
+			b.WriteString(part)
+		case initialisms[part]:
+			b.WriteString(strings.ToUpper(part))
+		default:
+			b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+		}
+	}
+	return b.String()
+}
+
+// plural is the English plural of a snake_case name, good enough for resource names
+func plural(name string) string {
+	switch {
+	case strings.HasSuffix(name, "s"), strings.HasSuffix(name, "x"), strings.HasSuffix(name, "ch"), strings.HasSuffix(name, "sh"):
+		return name + "es"
+	case strings.HasSuffix(name, "y") && len(name) > 1 && !strings.ContainsAny(name[len(name)-2:len(name)-1], "aeiou"):
+		return name[:len(name)-1] + "ies"
+	default:
+		return name + "s"
+	}
+}
+
+// Package is the Go package of the domain, e.g. orderitem
+func (d Domain) Package() string {
+	return strings.ReplaceAll(d.Name, "_", "")
+}
+
+// Type is the name of the entity, e.g. OrderItem
+func (d Domain) Type() string {
+	return camel(d.Name, true)
+}
+
+// Var is the variable holding an entity, e.g. orderItem
+func (d Domain) Var() string {
+	return camel(d.Name, false)
+}
+
+// Plural is the plural of Type, e.g. OrderItems
+func (d Domain) Plural() string {
+	return camel(plural(d.Name), true)
+}
+
+// VarPlural is the variable holding entities, e.g. orderItems
+func (d Domain) VarPlural() string {
+	return camel(plural(d.Name), false)
+}
+
+// Table is the database table, e.g. order_items
+func (d Domain) Table() string {
+	return plural(d.Name)
+}
+
+// Route is the path of the REST resource, e.g. order-items
+func (d Domain) Route() string {
+	return strings.ReplaceAll(plural(d.Name), "_", "-")
+}
+
+// Code is the prefix of the error codes, e.g. ORDER_ITEM
+func (d Domain) Code() string {
+	return strings.ToUpper(d.Name)
+}
+
+// HasTime reports whether a field is a time.Time, the files with the fields in signatures import time then
+func (d Domain) HasTime() bool {
+	for _, f := range d.Fields {
+		if f.Type == "time" {
+			return true
+		}
+	}
+	return false
+}
+
+// GoName is the name of the struct field, e.g. UserID
+func (f Field) GoName() string {
+	return camel(f.Name, true)
+}
+
+// Param is the name of the parameter holding the field, it doesn't shadow the names of the generated code
+func (f Field) Param() string {
+	p := camel(f.Name, false)
+	if token.IsKeyword(p) || reservedNames[p] {
+		return p + "Value"
+	}
+	return p
+}
+
+// GoType is the Go type of the field
+func (f Field) GoType() string {
+	if f.Type == "time" {
+		return "time.Time"
+	}
+	return f.Type
+}
+
+func (f Field) IsTime() bool {
+	return f.Type == "time"
+}
+
+// Binding is the binding tag of the field in the create request, numbers and bools can be zero
+func (f Field) Binding() string {
+	switch f.Type {
+	case "string", "time":
+		return "required"
+	default:
+		return ""
+	}
+}
+
+// Sample is a Go value of the field for the generated tests
+func (f Field) Sample() string {
+	switch f.Type {
+	case "string":
+		return `"test ` + strings.ReplaceAll(f.Name, "_", " ") + `"`
+	case "int", "int64":
+		return "42"
+	case "float64":
+		return "1.5"
+	case "bool":
+		return "true"
+	default:
+		return "time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)"
+	}
+}
+
+// SampleJSON is Sample as decoded from JSON into an interface{}, for the generated handler tests
+func (f Field) SampleJSON() string {
+	switch f.Type {
+	case "int", "int64":
+		return "float64(42)"
+	case "time":
+		return `"2025-01-02T03:04:05Z"`
+	default:
+		return f.Sample()
+	}
+}
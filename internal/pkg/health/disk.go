@@ -0,0 +1,25 @@
+//go:build linux || darwin
+
+package health
+
+import (
+	"context"
+	"fmt"
+	"syscall"
+)
+
+// DiskSpace checks that the filesystem of path has at least minFree bytes available
+func DiskSpace(path string, minFree uint64) Check {
+	return func(ctx context.Context) error {
+		var st syscall.Statfs_t
+		if err := syscall.Statfs(path, &st); err != nil {
+			return fmt.Errorf("failed to stat %s: %w", path, err)
+		}
+
+		free := uint64(st.Bavail) * uint64(st.Bsize)
+		if free < minFree {
+			return fmt.Errorf("%s has %d MB free, less than %d MB", path, free>>20, minFree>>20)
+		}
+		return nil
+	}
+}
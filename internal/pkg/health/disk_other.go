@@ -0,0 +1,12 @@
+//go:build !linux && !darwin
+
+package health
+
+import "context"
+
+// DiskSpace isn't supported on this platform, the check is always up
+func DiskSpace(path string, minFree uint64) Check {
+	return func(ctx context.Context) error {
+		return nil
+	}
+}
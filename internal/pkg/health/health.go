@@ -0,0 +1,213 @@
+// Package health runs the named checks of the components of the service (database, log sinks, disk space, ...)
+// for the readiness probe, the results are cached and every check has a timeout.
+package health
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Check reports whether a component works, it returns nil when healthy.
+// It should give up when ctx is done, a check running past its timeout is reported down anyway.
+type Check func(ctx context.Context) error
+
+type Status string
+
+const (
+	Up   Status = "up"
+	Down Status = "down"
+)
+
+// Result is the outcome of a check
+type Result struct {
+	Status     Status    `json:"status"`
+	Error      string    `json:"error,omitempty"`
+	DurationMs int64     `json:"duration_ms"`
+	CheckedAt  time.Time `json:"checked_at"`
+	// Cached is set when the result comes from a previous run, see WithCacheTTL
+	Cached bool `json:"cached,omitempty"`
+	// NonCritical is set for the checks that don't turn the readiness down, see NonCritical
+	NonCritical bool `json:"non_critical,omitempty"`
+}
+
+// Report is the outcome of every check, it's up when every critical check is up and the service isn't shutting down
+type Report struct {
+	Status       Status            `json:"status"`
+	ShuttingDown bool              `json:"shutting_down,omitempty"`
+	Checks       map[string]Result `json:"checks,omitempty"`
+}
+
+// Summary is a report without the errors and timings of the checks, which can name hosts, paths or DSNs.
+// It's the report served on the public port, the full one is on the admin server.
+type Summary struct {
+	Status       Status            `json:"status"`
+	ShuttingDown bool              `json:"shutting_down,omitempty"`
+	Checks       map[string]Status `json:"checks,omitempty"`
+}
+
+// Summary returns the status of every check of r
+func (r Report) Summary() Summary {
+	s := Summary{Status: r.Status, ShuttingDown: r.ShuttingDown}
+	if r.Checks != nil {
+		s.Checks = make(map[string]Status, len(r.Checks))
+		for name, result := range r.Checks {
+			s.Checks[name] = result.Status
+		}
+	}
+	return s
+}
+
+// Option sets how a check runs
+type Option func(*entry)
+
+// WithTimeout overrides the timeout of the registry for a check
+func WithTimeout(timeout time.Duration) Option {
+	return func(e *entry) {
+		e.timeout = timeout
+	}
+}
+
+// WithCacheTTL overrides how long the result of a check is reused, 0 runs the check on every probe
+func WithCacheTTL(ttl time.Duration) Option {
+	return func(e *entry) {
+		e.cacheTTL = ttl
+	}
+}
+
+// NonCritical reports the check without gating the readiness, for what the service can serve without,
+// e.g. the last run of a scheduled job
+func NonCritical() Option {
+	return func(e *entry) {
+		e.nonCritical = true
+	}
+}
+
+type entry struct {
+	check       Check
+	timeout     time.Duration
+	cacheTTL    time.Duration
+	nonCritical bool
+
+	// mu is held while the check runs, concurrent probes wait for its result instead of running it again
+	mu   sync.Mutex
+	last Result
+}
+
+// Registry holds the checks of the readiness probe
+type Registry struct {
+	timeout  time.Duration
+	cacheTTL time.Duration
+
+	mu           sync.RWMutex
+	checks       map[string]*entry
+	shuttingDown atomic.Bool
+}
+
+// NewRegistry returns an empty registry, timeout and cacheTTL apply to the checks registered without options
+func NewRegistry(timeout, cacheTTL time.Duration) *Registry {
+	return &Registry{
+		timeout:  timeout,
+		cacheTTL: cacheTTL,
+		checks:   map[string]*entry{},
+	}
+}
+
+// Register adds a check, a check registered with the same name is replaced
+func (r *Registry) Register(name string, check Check, opts ...Option) {
+	e := &entry{check: check, timeout: r.timeout, cacheTTL: r.cacheTTL}
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checks[name] = e
+}
+
+// Names returns the sorted names of the checks
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return slices.Sorted(maps.Keys(r.checks))
+}
+
+// Shutdown turns the readiness down for good, it's called as soon as the service starts shutting down
+func (r *Registry) Shutdown() {
+	r.shuttingDown.Store(true)
+}
+
+// ShuttingDown reports whether Shutdown was called
+func (r *Registry) ShuttingDown() bool {
+	return r.shuttingDown.Load()
+}
+
+// Ready runs every check concurrently, the checks aren't run once the service is shutting down
+func (r *Registry) Ready(ctx context.Context) Report {
+	if r.ShuttingDown() {
+		return Report{Status: Down, ShuttingDown: true}
+	}
+
+	r.mu.RLock()
+	checks := maps.Clone(r.checks)
+	r.mu.RUnlock()
+
+	report := Report{Status: Up, Checks: make(map[string]Result, len(checks))}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for name, e := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result := e.run(ctx)
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[name] = result
+			if result.Status != Up && !result.NonCritical {
+				report.Status = Down
+			}
+		}()
+	}
+	wg.Wait()
+	return report
+}
+
+func (e *entry) run(ctx context.Context) Result {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if !e.last.CheckedAt.IsZero() && time.Since(e.last.CheckedAt) < e.cacheTTL {
+		cached := e.last
+		cached.Cached = true
+		return cached
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, e.timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		done <- e.check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = fmt.Errorf("timed out after %s", e.timeout)
+	}
+
+	result := Result{Status: Up, DurationMs: time.Since(start).Milliseconds(), CheckedAt: start, NonCritical: e.nonCritical}
+	if err != nil {
+		result.Status = Down
+		result.Error = err.Error()
+	}
+	e.last = result
+	return result
+}
@@ -0,0 +1,96 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestReady(t *testing.T) {
+	r := NewRegistry(time.Second, 0)
+	r.Register("db", func(ctx context.Context) error { return nil })
+	r.Register("cache", func(ctx context.Context) error { return errors.New("connection refused") })
+	r.Register("slow", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}, WithTimeout(20*time.Millisecond))
+	r.Register("stuck", func(ctx context.Context) error {
+		// ignores ctx, it's reported down on timeout anyway
+		time.Sleep(time.Second)
+		return nil
+	}, WithTimeout(20*time.Millisecond))
+
+	report := r.Ready(context.Background())
+	if report.Status != Down {
+		t.Errorf("expected down, got %s", report.Status)
+	}
+	if got := report.Checks["db"]; got.Status != Up {
+		t.Errorf("expected db up, got %+v", got)
+	}
+	if got := report.Checks["cache"]; got.Status != Down || got.Error != "connection refused" {
+		t.Errorf("expected cache down, got %+v", got)
+	}
+	for _, name := range []string{"slow", "stuck"} {
+		if got := report.Checks[name]; got.Status != Down || !strings.Contains(got.Error, "timed out") {
+			t.Errorf("expected %s to time out, got %+v", name, got)
+		}
+	}
+}
+
+func TestReadyNonCritical(t *testing.T) {
+	r := NewRegistry(time.Second, 0)
+	r.Register("db", func(ctx context.Context) error { return nil })
+	r.Register("scheduler/report", func(ctx context.Context) error { return errors.New("last run failed") }, NonCritical())
+
+	report := r.Ready(context.Background())
+	if report.Status != Up {
+		t.Errorf("expected a failing non-critical check to keep the readiness up, got %s", report.Status)
+	}
+	if got := report.Checks["scheduler/report"]; got.Status != Down || !got.NonCritical || got.Error != "last run failed" {
+		t.Errorf("expected the non-critical check to be reported down, got %+v", got)
+	}
+}
+
+func TestReadyCache(t *testing.T) {
+	var runs atomic.Int32
+	r := NewRegistry(time.Second, time.Minute)
+	r.Register("db", func(ctx context.Context) error {
+		runs.Add(1)
+		return nil
+	})
+	r.Register("logger", func(ctx context.Context) error {
+		runs.Add(1)
+		return nil
+	}, WithCacheTTL(0))
+
+	first := r.Ready(context.Background())
+	second := r.Ready(context.Background())
+
+	if runs.Load() != 3 {
+		t.Errorf("expected db to run once and logger twice, got %d runs", runs.Load())
+	}
+	if first.Checks["db"].Cached || !second.Checks["db"].Cached {
+		t.Errorf("expected the second db result to be cached, got %+v then %+v", first.Checks["db"], second.Checks["db"])
+	}
+	if second.Checks["logger"].Cached {
+		t.Error("expected logger not to be cached")
+	}
+}
+
+func TestReadyShuttingDown(t *testing.T) {
+	r := NewRegistry(time.Second, 0)
+	r.Register("db", func(ctx context.Context) error { return nil })
+
+	if report := r.Ready(context.Background()); report.Status != Up {
+		t.Fatalf("expected up, got %+v", report)
+	}
+
+	r.Shutdown()
+	report := r.Ready(context.Background())
+	if report.Status != Down || !report.ShuttingDown || len(report.Checks) != 0 {
+		t.Errorf("expected down without running the checks, got %+v", report)
+	}
+}
@@ -0,0 +1,225 @@
+// Package listener opens the listeners of the servers: TCP, unix sockets, or sockets inherited from systemd socket activation
+// or from the previous process on an upgrade (see Upgrade), so a new binary takes over without dropping connections.
+package listener
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"starter-go/internal/pkg/config"
+	"starter-go/internal/pkg/logger"
+)
+
+// Networks of config.ListenerConfig
+const (
+	TCP     = "tcp"
+	Unix    = "unix"
+	Systemd = "systemd"
+)
+
+// DefaultName is the name of the listener of the public server, it takes the only inherited socket when it isn't named
+const DefaultName = "http"
+
+// Env vars of systemd socket activation, see sd_listen_fds(3), the passed descriptors start at 3
+const (
+	envListenFDs     = "LISTEN_FDS"
+	envListenPID     = "LISTEN_PID"
+	envListenFDNames = "LISTEN_FDNAMES"
+	listenFDsStart   = 3
+)
+
+var (
+	inheritOnce sync.Once
+	// inherited are the listeners passed to the process and not taken yet, keyed by name
+	inherited map[string]net.Listener
+	// inheritedOrder are the names of inherited, in descriptor order
+	inheritedOrder []string
+	inheritErr     error
+
+	mu sync.Mutex
+	// active are the listeners opened by Listen and not closed, handed to the new process on upgrade
+	active []*namedListener
+)
+
+// namedListener is a listener opened by Listen, it's forgotten once closed
+type namedListener struct {
+	net.Listener
+	name string
+	// handedOver is set once the new process of an upgrade accepts the connections
+	handedOver atomic.Bool
+	closed     chan struct{}
+	closeOnce  sync.Once
+}
+
+// Accept returns the next connection, once the listener is handed over it blocks until closed,
+// the connections queued meanwhile are accepted by the new process. A connection accepted while handing over is still returned.
+func (l *namedListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil && l.handedOver.Load() {
+		<-l.closed
+		return nil, net.ErrClosed
+	}
+	return conn, err
+}
+
+func (l *namedListener) Close() error {
+	mu.Lock()
+	for i, a := range active {
+		if a == l {
+			active = append(active[:i], active[i+1:]...)
+			break
+		}
+	}
+	mu.Unlock()
+	l.closeOnce.Do(func() {
+		close(l.closed)
+	})
+	return l.Listener.Close()
+}
+
+// handOver stops accepting connections, the pending Accept is interrupted by a deadline in the past
+func (l *namedListener) handOver() {
+	l.handedOver.Store(true)
+	if d, ok := l.Listener.(interface{ SetDeadline(time.Time) error }); ok {
+		_ = d.SetDeadline(time.Now())
+	}
+}
+
+// Listen returns the listener of the server name: the one inherited under that name when there's one,
+// otherwise a new one on the network of conf, addr is the TCP address. A nil conf listens on TCP.
+func Listen(ctx context.Context, name string, conf config.ListenerConfig, addr string) (net.Listener, error) {
+	network := TCP
+	if conf != nil {
+		network = conf.GetNetwork()
+	}
+
+	ln, err := take(name)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case ln != nil:
+		logger.Info("[Listener] using inherited listener", "name", name, "addr", ln.Addr().String())
+		// the socket path is this process' own, systemd keeps the one of its socket units
+		if l, ok := ln.(*net.UnixListener); ok && network == Unix {
+			l.SetUnlinkOnClose(true)
+		}
+	case network == Systemd:
+		return nil, fmt.Errorf("no socket passed by systemd (%s) for %s, name it with FileDescriptorName=%s", envListenFDs, name, name)
+	case network == Unix:
+		if ln, err = listenUnix(ctx, conf.GetSocketPath(), conf.GetSocketMode()); err != nil {
+			return nil, fmt.Errorf("failed to listen on %s: %w", conf.GetSocketPath(), err)
+		}
+	default:
+		var lc net.ListenConfig
+		if ln, err = lc.Listen(ctx, TCP, addr); err != nil {
+			return nil, fmt.Errorf("failed to listen on %s: %w", addr, err)
+		}
+	}
+
+	named := &namedListener{Listener: ln, name: name, closed: make(chan struct{})}
+	mu.Lock()
+	active = append(active, named)
+	mu.Unlock()
+	return named, nil
+}
+
+// listenUnix listens on the socket path with mode.
+// A socket left by a process that didn't exit cleanly is replaced, one still accepting connections is in use.
+func listenUnix(ctx context.Context, path string, mode os.FileMode) (net.Listener, error) {
+	if info, err := os.Stat(path); err == nil && info.Mode()&os.ModeSocket != 0 {
+		if conn, err := net.DialTimeout(Unix, path, time.Second); err == nil {
+			_ = conn.Close()
+			return nil, errors.New("the socket is in use")
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, err
+		}
+	}
+
+	var lc net.ListenConfig
+	ln, err := lc.Listen(ctx, Unix, path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, mode); err != nil {
+		_ = ln.Close()
+		return nil, err
+	}
+	return ln, nil
+}
+
+// take returns the inherited listener of name, nil when there's none.
+// The default name also takes the only inherited listener, whatever its name.
+func take(name string) (net.Listener, error) {
+	inheritOnce.Do(func() {
+		inheritErr = inherit()
+	})
+	if inheritErr != nil {
+		return nil, inheritErr
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	key := name
+	if _, ok := inherited[key]; !ok && name == DefaultName && len(inheritedOrder) == 1 {
+		key = inheritedOrder[0]
+	}
+	ln, ok := inherited[key]
+	if !ok {
+		return nil, nil
+	}
+	delete(inherited, key)
+	return ln, nil
+}
+
+// inherit reads the descriptors passed to the process, by systemd when LISTEN_PID is the process,
+// or by the previous process on upgrade when it's the parent. The env vars are unset so child processes don't use them.
+func inherit() error {
+	defer func() {
+		for _, env := range []string{envListenFDs, envListenPID, envListenFDNames, envUpgradeParentPID, envUpgradeReadyFD} {
+			_ = os.Unsetenv(env)
+		}
+	}()
+
+	fromSystemd := os.Getenv(envListenPID) == strconv.Itoa(os.Getpid())
+	fromParent := os.Getenv(envListenPID) == "" && os.Getenv(envUpgradeParentPID) == strconv.Itoa(os.Getppid())
+	if !fromSystemd && !fromParent {
+		return nil
+	}
+	if fd, err := strconv.Atoi(os.Getenv(envUpgradeReadyFD)); fromParent && err == nil {
+		readyFile = os.NewFile(uintptr(fd), "upgrade-ready")
+	}
+
+	n, _ := strconv.Atoi(os.Getenv(envListenFDs))
+
+	names := strings.Split(os.Getenv(envListenFDNames), ":")
+	inherited = map[string]net.Listener{}
+	for i := 0; i < n; i++ {
+		fd := listenFDsStart + i
+		name := fmt.Sprintf("fd%d", fd)
+		if i < len(names) && names[i] != "" {
+			name = names[i]
+		}
+
+		f := os.NewFile(uintptr(fd), name)
+		ln, err := net.FileListener(f)
+		// FileListener works on a copy of the descriptor
+		_ = f.Close()
+		if err != nil {
+			return fmt.Errorf("inherited descriptor %d (%s) isn't a listener: %w", fd, name, err)
+		}
+		inherited[name] = ln
+		inheritedOrder = append(inheritedOrder, name)
+	}
+	return nil
+}
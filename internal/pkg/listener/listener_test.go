@@ -0,0 +1,170 @@
+//go:build unix
+
+package listener
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// envTestChild makes the test binary run as the new process of an upgrade instead of running the tests
+const envTestChild = "LISTENER_TEST_CHILD"
+
+func TestMain(m *testing.M) {
+	switch os.Getenv(envTestChild) {
+	case "serve":
+		os.Exit(serveChild())
+	case "fail":
+		os.Exit(1)
+	}
+	os.Exit(m.Run())
+}
+
+// serveChild takes over the listener, tells the parent it's ready and serves its pid until killed
+func serveChild() int {
+	ln, err := Listen(context.Background(), DefaultName, nil, "")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	Ready()
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, strconv.Itoa(os.Getpid()))
+	})}
+	go func() {
+		time.Sleep(30 * time.Second)
+		_ = srv.Close()
+	}()
+	_ = srv.Serve(ln)
+	return 0
+}
+
+type testListenerConfig struct {
+	network string
+	path    string
+	mode    os.FileMode
+}
+
+func (c testListenerConfig) GetNetwork() string         { return c.network }
+func (c testListenerConfig) GetSocketPath() string      { return c.path }
+func (c testListenerConfig) GetSocketMode() os.FileMode { return c.mode }
+func (c testListenerConfig) GetUpgradeTimeout() uint    { return 10000 }
+
+func TestListenUnix(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.sock")
+	conf := testListenerConfig{network: Unix, path: path, mode: 0o600}
+
+	// a socket left by a process that didn't exit cleanly
+	stale, err := net.Listen(Unix, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	_ = stale.Close()
+
+	ln, err := Listen(context.Background(), DefaultName, conf, "")
+	if err != nil {
+		t.Fatalf("stale socket: %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode := info.Mode().Perm(); mode != 0o600 {
+		t.Errorf("mode = %o, want 600", mode)
+	}
+
+	if _, err := Listen(context.Background(), DefaultName, conf, ""); err == nil || !strings.Contains(err.Error(), "in use") {
+		t.Errorf("socket in use: err = %v", err)
+	}
+
+	if got := len(active); got != 1 {
+		t.Errorf("active listeners = %d, want 1", got)
+	}
+	_ = ln.Close()
+	if got := len(active); got != 0 {
+		t.Errorf("active listeners after close = %d, want 0", got)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("socket not removed on close: %v", err)
+	}
+}
+
+func TestListenSystemdWithoutSocket(t *testing.T) {
+	_, err := Listen(context.Background(), DefaultName, testListenerConfig{network: Systemd}, "")
+	if err == nil || !strings.Contains(err.Error(), "FileDescriptorName=http") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestUpgrade(t *testing.T) {
+	ln, err := Listen(context.Background(), DefaultName, nil, "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	addr := ln.Addr().String()
+
+	t.Run("new process exits", func(t *testing.T) {
+		t.Setenv(envTestChild, "fail")
+		if err := Upgrade(10 * time.Second); err == nil || !strings.Contains(err.Error(), "exited before being ready") {
+			t.Errorf("err = %v", err)
+		}
+	})
+
+	t.Run("new process takes over", func(t *testing.T) {
+		srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = io.WriteString(w, strconv.Itoa(os.Getpid()))
+		})}
+		go func() { _ = srv.Serve(ln) }()
+		defer srv.Close()
+
+		t.Setenv(envTestChild, "serve")
+		if err := Upgrade(10 * time.Second); err != nil {
+			t.Fatal(err)
+		}
+
+		// this process stops accepting, the new connections go to the new process,
+		// except one racing with the handover on the Accept pending here, it's served before draining
+		client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+		children := map[int]bool{}
+		servedHere := 0
+		for i := 0; i < 20; i++ {
+			resp, err := client.Get("http://" + addr)
+			if err != nil {
+				t.Fatalf("new process not serving on %s: %v", addr, err)
+			}
+			body, _ := io.ReadAll(resp.Body)
+			_ = resp.Body.Close()
+			pid, _ := strconv.Atoi(string(body))
+			if pid == os.Getpid() {
+				servedHere++
+				continue
+			}
+			if pid == 0 {
+				t.Fatalf("request %d: unexpected body %q", i, body)
+			}
+			if !children[pid] {
+				children[pid] = true
+				t.Cleanup(func() {
+					if p, err := os.FindProcess(pid); err == nil {
+						_ = p.Kill()
+						_, _ = p.Wait()
+					}
+				})
+			}
+		}
+		if servedHere > 1 {
+			t.Errorf("%d requests served by this process after the upgrade, want at most 1", servedHere)
+		}
+	})
+}
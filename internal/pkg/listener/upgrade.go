@@ -0,0 +1,169 @@
+package listener
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"starter-go/internal/pkg/logger"
+)
+
+// Env vars of the upgrade, set by the previous process for the new one
+const (
+	envUpgradeParentPID = "APP_UPGRADE_PARENT_PID"
+	envUpgradeReadyFD   = "APP_UPGRADE_READY_FD"
+)
+
+var (
+	// upgrading is set while a new process is starting
+	upgrading bool
+	// readyFile is written by Ready to tell the previous process this one is serving, nil when the process wasn't started by Upgrade
+	readyFile *os.File
+)
+
+// Upgrade starts a new process of the binary, with the same arguments and env, handing it the listeners opened by Listen.
+// It returns once the new process called Ready: the listeners then stop accepting connections in this process, and the caller drains and exits.
+// When the new process exits or isn't ready within timeout, it's killed and an error is returned, the current process keeps serving.
+func Upgrade(timeout time.Duration) error {
+	mu.Lock()
+	if upgrading {
+		mu.Unlock()
+		return errors.New("an upgrade is already in progress")
+	}
+	upgrading = true
+	listeners := append([]*namedListener(nil), active...)
+	mu.Unlock()
+	defer func() {
+		mu.Lock()
+		upgrading = false
+		mu.Unlock()
+	}()
+
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	files := make([]*os.File, 0, len(listeners)+1)
+	names := make([]string, 0, len(listeners))
+	defer func() {
+		for _, f := range files {
+			_ = f.Close()
+		}
+	}()
+	for _, l := range listeners {
+		f, err := file(l.Listener)
+		if err != nil {
+			return fmt.Errorf("listener %s can't be handed over: %w", l.name, err)
+		}
+		files = append(files, f)
+		names = append(names, l.name)
+	}
+
+	// the new process closes its end of the pipe once ready, or when it exits
+	ready, readyW, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	defer ready.Close()
+	files = append(files, readyW)
+
+	cmd := exec.Command(exe, os.Args[1:]...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	cmd.ExtraFiles = files
+	cmd.Env = append(upgradeEnv(),
+		envListenFDs+"="+strconv.Itoa(len(listeners)),
+		envListenFDNames+"="+strings.Join(names, ":"),
+		envUpgradeParentPID+"="+strconv.Itoa(os.Getpid()),
+		envUpgradeReadyFD+"="+strconv.Itoa(listenFDsStart+len(listeners)),
+	)
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start %s: %w", exe, err)
+	}
+	logger.Info("[Upgrade] new process started, waiting for it to be ready", "pid", cmd.Process.Pid, "listeners", strings.Join(names, ","))
+	// only the new process holds the write end now
+	_ = readyW.Close()
+	files = files[:len(files)-1]
+
+	done := make(chan bool, 1)
+	go func() {
+		b := make([]byte, 1)
+		n, _ := ready.Read(b)
+		done <- n == 1
+	}()
+
+	select {
+	case ok := <-done:
+		if ok {
+			// the new process outlives this one, it's not waited for
+			_ = cmd.Process.Release()
+			for _, l := range listeners {
+				l.handOver()
+			}
+			return nil
+		}
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+		return fmt.Errorf("new process %d exited before being ready", cmd.Process.Pid)
+	case <-time.After(timeout):
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+		return fmt.Errorf("new process %d wasn't ready after %s, killed", cmd.Process.Pid, timeout)
+	}
+}
+
+// Ready tells the previous process this one is serving, after an upgrade, so it drains and exits.
+// It's called once the servers are listening, the inherited listeners no server took are closed.
+// It does nothing else when the process wasn't started by Upgrade.
+func Ready() {
+	inheritOnce.Do(func() {
+		inheritErr = inherit()
+	})
+
+	mu.Lock()
+	f := readyFile
+	readyFile = nil
+	for name, ln := range inherited {
+		logger.Warn("[Listener] closing inherited listener not used by any server", "name", name, "addr", ln.Addr().String())
+		_ = ln.Close()
+		delete(inherited, name)
+	}
+	mu.Unlock()
+	if f == nil {
+		return
+	}
+	_, _ = f.Write([]byte{1})
+	_ = f.Close()
+}
+
+// file returns a copy of the descriptor of ln, a unix socket is kept on disk when this process closes it
+func file(ln net.Listener) (*os.File, error) {
+	switch l := ln.(type) {
+	case *net.TCPListener:
+		return l.File()
+	case *net.UnixListener:
+		l.SetUnlinkOnClose(false)
+		return l.File()
+	default:
+		return nil, fmt.Errorf("unsupported listener %T", ln)
+	}
+}
+
+// upgradeEnv is the env of the process without the variables of a previous upgrade or socket activation
+func upgradeEnv() []string {
+	var env []string
+	for _, kv := range os.Environ() {
+		name, _, _ := strings.Cut(kv, "=")
+		switch name {
+		case envListenFDs, envListenPID, envListenFDNames, envUpgradeParentPID, envUpgradeReadyFD:
+			continue
+		}
+		env = append(env, kv)
+	}
+	return env
+}
@@ -3,6 +3,9 @@ package logger
 import "time"
 
 type LogConfig struct {
+	Level LogLevel
+	// Location of the log timestamps, the host location when nil
+	Location       *time.Location
 	EnableStdout   bool
 	EnableLogFile  bool
 	EnableELK      bool
@@ -0,0 +1,66 @@
+package ctxfield
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+type key string
+
+const (
+	ctxFields key = "ctx_fields"
+)
+
+// fields is shared by every context derived from the one returned by New,
+// so values set deep in a request (e.g. flag evaluations) show up in the logs of the whole request
+type fields struct {
+	mu     sync.Mutex
+	values map[string]interface{}
+}
+
+// New returns a context able to carry fields, every *Ctx log of ctx includes them
+func New(ctx context.Context) context.Context {
+	return context.WithValue(ctx, ctxFields, &fields{values: map[string]interface{}{}})
+}
+
+// Set adds or replaces a field, it does nothing when ctx wasn't created by New
+func Set(ctx context.Context, name string, value interface{}) {
+	f := get(ctx)
+	if f == nil {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.values[name] = value
+}
+
+// Values returns the fields of ctx as key value pairs, sorted by key
+func Values(ctx context.Context) []interface{} {
+	f := get(ctx)
+	if f == nil {
+		return nil
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	names := make([]string, 0, len(f.values))
+	for name := range f.values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	kv := make([]interface{}, 0, 2*len(names))
+	for _, name := range names {
+		kv = append(kv, name, f.values[name])
+	}
+	return kv
+}
+
+func get(ctx context.Context) *fields {
+	if ctx == nil {
+		return nil
+	}
+	f, _ := ctx.Value(ctxFields).(*fields)
+	return f
+}
@@ -1,6 +1,8 @@
 package logger
 
 import (
+	"time"
+
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
@@ -9,7 +11,8 @@ var (
 	timeKey = "timestamp"
 )
 
-func zapJSONEncoder() zapcore.Encoder {
+// zapJSONEncoder creates the encoder of every log, timestamps are written in loc (the host location when nil)
+func zapJSONEncoder(loc *time.Location) zapcore.Encoder {
 	return zapcore.NewJSONEncoder(zapcore.EncoderConfig{
 		TimeKey:        timeKey,
 		LevelKey:       "level",
@@ -20,12 +23,21 @@ func zapJSONEncoder() zapcore.Encoder {
 		StacktraceKey:  "stacktrace",
 		LineEnding:     zapcore.DefaultLineEnding,
 		EncodeLevel:    zapcore.LowercaseLevelEncoder,
-		EncodeTime:     zapcore.RFC3339NanoTimeEncoder,
+		EncodeTime:     timeEncoder(loc),
 		EncodeDuration: zapcore.StringDurationEncoder,
 		EncodeCaller:   zapcore.ShortCallerEncoder,
 	})
 }
 
+func timeEncoder(loc *time.Location) zapcore.TimeEncoder {
+	if loc == nil {
+		return zapcore.RFC3339NanoTimeEncoder
+	}
+	return func(t time.Time, enc zapcore.PrimitiveArrayEncoder) {
+		zapcore.RFC3339NanoTimeEncoder(t.In(loc), enc)
+	}
+}
+
 func zapLevel() zapcore.LevelEnabler {
 	return zap.LevelEnablerFunc(func(level zapcore.Level) bool {
 		return level >= zapcore.DebugLevel
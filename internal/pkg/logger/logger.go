@@ -6,7 +6,10 @@ import (
 	"fmt"
 	"os"
 	"starter-go/internal/pkg/logger/contextid"
+	"starter-go/internal/pkg/logger/ctxfield"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"go.uber.org/zap"
@@ -15,17 +18,40 @@ import (
 	"moul.io/zapfilter"
 )
 
-// DefaultLogger Skip 1 Caller because default logger method will call log method from the Logger struct
-var DefaultLogger = New(AddWriter(os.Stdout, false), WithCaller(1))
+// defaultLogger is read by every goroutine logging, it's swapped atomically by SetDefaultLogger and ReplaceDefaultLogger
+var defaultLogger atomic.Pointer[Logger]
+
+func init() {
+	// Skip 1 Caller because default logger method will call log method from the Logger struct
+	l := New(AddWriter(os.Stdout, false), WithCaller(1))
+	defaultLogger.Store(&l)
+}
 
 // Logger wrap underlying logger library
 type Logger struct {
 	logger    *zap.SugaredLogger
 	threshold LogLevel
 	stopFn    func()
+	// log files, see SinkErr
+	sinks []*sink
+	// writes through the default logger in flight, shared by the copies made by With
+	writes *writes
+}
+
+// writes lets Stop wait for the writes in flight before closing the log files
+type writes struct {
+	mu      sync.RWMutex
+	stopped bool
 }
 
+// Stop flushes the logger and closes its log files, once the writes in flight through the default logger are done
 func (l *Logger) Stop() {
+	if l.writes != nil {
+		l.writes.mu.Lock()
+		l.writes.stopped = true
+		l.writes.mu.Unlock()
+	}
+
 	if l.stopFn == nil {
 		return
 	}
@@ -51,9 +77,67 @@ func (l *Logger) SetThreshold(level LogLevel) {
 	l.threshold = level
 }
 
-// Set the global DefaultLogger to l
+// ParseLevel converts a level name (DEBUG, INFO, WARN, ERROR, OFF) to LogLevel, case insensitive
+func ParseLevel(level string) (LogLevel, error) {
+	switch strings.ToUpper(level) {
+	case "DEBUG":
+		return DEBUG, nil
+	case "INFO":
+		return INFO, nil
+	case "WARN":
+		return WARN, nil
+	case "ERROR":
+		return ERROR, nil
+	case "OFF":
+		return OFF, nil
+	default:
+		return INFO, fmt.Errorf("unknown log level %q", level)
+	}
+}
+
+// Set the global default logger to l
 func SetDefaultLogger(l Logger) {
-	DefaultLogger = l
+	defaultLogger.Store(&l)
+}
+
+// ReplaceDefaultLogger sets the global default logger to l and stops the previous one,
+// once the writes still in flight through it are done, e.g. when the logger config is reloaded
+func ReplaceDefaultLogger(l *Logger) {
+	previous := defaultLogger.Swap(l)
+	if previous != nil && previous != l {
+		previous.Stop()
+	}
+}
+
+// Default returns the global default logger
+func Default() *Logger {
+	return defaultLogger.Load()
+}
+
+// acquire returns the default logger to write through, when held it isn't closed by Stop until release.
+// A logger stopped once replaced is skipped for the new default one.
+func acquire() (*Logger, bool) {
+	for {
+		l := defaultLogger.Load()
+		if l.writes == nil {
+			return l, false
+		}
+		l.writes.mu.RLock()
+		if !l.writes.stopped {
+			return l, true
+		}
+		l.writes.mu.RUnlock()
+		if defaultLogger.Load() == l {
+			// stopped without being replaced, e.g. on exit
+			return l, false
+		}
+	}
+}
+
+func release(l *Logger, held bool) {
+	if held {
+		l.writes.mu.RUnlock()
+	}
 }
 
 // New instantiates new logger
@@ -63,7 +147,7 @@ func New(opts ...Option) Logger {
 		opt(&conf)
 	}
 
-	jsonEncoder := zapJSONEncoder()
+	jsonEncoder := zapJSONEncoder(nil)
 	level := zapLevel()
 	var cores []zapcore.Core
 	for _, ws := range conf.ws {
@@ -80,21 +164,23 @@ func New(opts ...Option) Logger {
 	defer logger.Sync()
 	L := logger.Sugar()
 
-	return Logger{logger: L, threshold: INFO}
+	return Logger{logger: L, threshold: INFO, writes: &writes{}}
 }
 
 // Instantiates new logger based on config supplied by user
 //
 // When enabling `EnableELK`, another goroutine is spawned to flush the buffer periodically.
-// Logger's Stop() function must be deferred call after calling this function.
+// Logger's Stop() function must be deferred call after calling this function,
+// it also closes the log files so a replaced logger doesn't keep them open.
 func NewFromConfig(conf LogConfig) (*Logger, error) {
 	var cores []zapcore.Core
+	var files []*sink
 
 	if !conf.EnableLogFile && !conf.EnableStdout && !conf.EnableELK {
 		return nil, errors.New("invalid configuration, must enable stdout or logfile or elk")
 	}
 
-	jsonEncoder := zapJSONEncoder()
+	jsonEncoder := zapJSONEncoder(conf.Location)
 
 	if conf.EnableLogFile {
 		if len(conf.LogFileConfigs) == 0 {
@@ -102,12 +188,13 @@ func NewFromConfig(conf LogConfig) (*Logger, error) {
 		}
 
 		for _, logFileConfig := range conf.LogFileConfigs {
-			core, err := createFileHandlerCore(jsonEncoder, logFileConfig)
+			core, writer, err := createFileHandlerCore(jsonEncoder, logFileConfig)
 			if err != nil {
 				return nil, err
 			}
 
 			cores = append(cores, core)
+			files = append(files, writer)
 		}
 	}
 
@@ -116,23 +203,28 @@ func NewFromConfig(conf LogConfig) (*Logger, error) {
 		cores = append(cores, core)
 	}
 
-	var stopFn func()
-
 	L := createZapLogger(cores, conf.CallerSkipSet, conf.CallerSkip)
 
-	return &Logger{logger: L, threshold: INFO, stopFn: stopFn}, nil
+	stopFn := func() {
+		_ = L.Sync()
+		for _, f := range files {
+			_ = f.Close()
+		}
+	}
+
+	return &Logger{logger: L, threshold: conf.Level, stopFn: stopFn, sinks: files, writes: &writes{}}, nil
 }
 
 // Create zap core that specifically handle writing log to file
-func createFileHandlerCore(encoder zapcore.Encoder, logFileConfig LogFileConfig) (zapcore.Core, error) {
-	writer := &lumberjack.Logger{
+func createFileHandlerCore(encoder zapcore.Encoder, logFileConfig LogFileConfig) (zapcore.Core, *sink, error) {
+	writer := &sink{Logger: &lumberjack.Logger{
 		Filename:   logFileConfig.FullpathFilename,
 		MaxSize:    logFileConfig.MaxSize,
 		MaxBackups: logFileConfig.MaxBackups,
 		MaxAge:     logFileConfig.MaxAge,
 		LocalTime:  logFileConfig.LocalTime,
 		Compress:   logFileConfig.Compress,
-	}
+	}}
 	writeSyncer := zapcore.Lock(zapcore.AddSync(writer))
 
 	core := zapcore.NewCore(encoder, writeSyncer, zapLevel())
@@ -145,11 +237,11 @@ func createFileHandlerCore(encoder zapcore.Encoder, logFileConfig LogFileConfig)
 
 	levelFilterFunction, err := zapfilter.ParseRules(filter)
 	if err != nil {
-		return nil, fmt.Errorf("%s: %s", "invalid log file levels configuration", err.Error())
+		return nil, nil, fmt.Errorf("%s: %s", "invalid log file levels configuration", err.Error())
 	}
 	filteredCore := zapfilter.NewFilteringCore(core, levelFilterFunction)
 
-	return filteredCore, nil
+	return filteredCore, writer, nil
 }
 
 // Create zap core that specifically handle writing log to stdout
@@ -177,24 +269,32 @@ func createZapLogger(cores []zapcore.Core, callerSkipSet bool, callerSkip int) *
 
 // Debug using the default logger to log the message on debug level with additional key value when provided
 func Debug(msg string, kv ...interface{}) {
-	DefaultLogger.Debug(msg, kv...)
+	l, held := acquire()
+	defer release(l, held)
+	l.Debug(msg, kv...)
 }
 
 // Info using the default logger to log the message on info level with additional key value when provided
 func Info(msg string, kv ...interface{}) {
-	DefaultLogger.Info(msg, kv...)
+	l, held := acquire()
+	defer release(l, held)
+	l.Info(msg, kv...)
 }
 
 // Access using the default logger to log the message on info level with additional key value when provided
 // This function is created to differentiate the log used for access logging purpose
 // When the logs are written to file it should be written to different log file (e.g. Info to data.log, while Access to access.log)
 func Access(msg string, kv ...interface{}) {
-	DefaultLogger.Access(msg, kv...)
+	l, held := acquire()
+	defer release(l, held)
+	l.Access(msg, kv...)
 }
 
 // Warn using the default logger to log the message on warn level with additional key value when provided
 func Warn(msg string, kv ...interface{}) {
-	DefaultLogger.Warn(msg, kv...)
+	l, held := acquire()
+	defer release(l, held)
+	l.Warn(msg, kv...)
 }
 
 // Error using the default logger to log the message on error level with the error detail and additional key value when provided
@@ -202,27 +302,37 @@ func Warn(msg string, kv ...interface{}) {
 //
 //go:noinline
 func Error(msg string, kv ...interface{}) {
-	DefaultLogger.Error(msg, kv...)
+	l, held := acquire()
+	defer release(l, held)
+	l.Error(msg, kv...)
 }
 
 // DebugCtx using the default logger to log the message on debug level with additional key value when provided
 func DebugCtx(ctx context.Context, msg string, kv ...interface{}) {
-	DefaultLogger.DebugCtx(ctx, msg, kv...)
+	l, held := acquire()
+	defer release(l, held)
+	l.DebugCtx(ctx, msg, kv...)
 }
 
 // InfoCtx using the default logger to log the message on info level with additional key value when provided
 func InfoCtx(ctx context.Context, msg string, kv ...interface{}) {
-	DefaultLogger.InfoCtx(ctx, msg, kv...)
+	l, held := acquire()
+	defer release(l, held)
+	l.InfoCtx(ctx, msg, kv...)
 }
 
 // Same with Access, with additional key value for context
 func AccessCtx(ctx context.Context, msg string, kv ...interface{}) {
-	DefaultLogger.AccessCtx(ctx, msg, kv...)
+	l, held := acquire()
+	defer release(l, held)
+	l.AccessCtx(ctx, msg, kv...)
 }
 
 // WarnCtx using the default logger to log the message on warn level with additional key value when provided
 func WarnCtx(ctx context.Context, msg string, kv ...interface{}) {
-	DefaultLogger.WarnCtx(ctx, msg, kv...)
+	l, held := acquire()
+	defer release(l, held)
+	l.WarnCtx(ctx, msg, kv...)
 }
 
 // ErrorCtx using the default logger to log the message on error level with the error detail and additional key value when provided
@@ -230,7 +340,9 @@ func WarnCtx(ctx context.Context, msg string, kv ...interface{}) {
 //
 //go:noinline
 func ErrorCtx(ctx context.Context, msg string, kv ...interface{}) {
-	DefaultLogger.ErrorCtx(ctx, msg, kv...)
+	l, held := acquire()
+	defer release(l, held)
+	l.ErrorCtx(ctx, msg, kv...)
 }
 
 // Deprecated: Timestamp will be set by default
@@ -301,6 +413,7 @@ func (l Logger) DebugCtx(ctx context.Context, msg string, kv ...interface{}) {
 	if contextID != "" {
 		kv = append(kv, "context_id", contextID)
 	}
+	kv = append(kv, ctxfield.Values(ctx)...)
 
 	l.logger.Debugw(msg, mask(kv...)...)
 }
@@ -315,6 +428,7 @@ func (l Logger) InfoCtx(ctx context.Context, msg string, kv ...interface{}) {
 	if contextID != "" {
 		kv = append(kv, "context_id", contextID)
 	}
+	kv = append(kv, ctxfield.Values(ctx)...)
 
 	l.logger.Infow(msg, mask(kv...)...)
 }
@@ -329,6 +443,7 @@ func (l Logger) AccessCtx(ctx context.Context, msg string, kv ...interface{}) {
 	if contextID != "" {
 		kv = append(kv, "context_id", contextID)
 	}
+	kv = append(kv, ctxfield.Values(ctx)...)
 
 	l.logger.Named("access").Infow(msg, mask(kv...)...)
 }
@@ -343,6 +458,7 @@ func (l Logger) WarnCtx(ctx context.Context, msg string, kv ...interface{}) {
 	if contextID != "" {
 		kv = append(kv, "context_id", contextID)
 	}
+	kv = append(kv, ctxfield.Values(ctx)...)
 
 	l.logger.Warnw(msg, mask(kv...)...)
 }
@@ -357,6 +473,7 @@ func (l Logger) ErrorCtx(ctx context.Context, msg string, kv ...interface{}) {
 	if contextID != "" {
 		kv = append(kv, "context_id", contextID)
 	}
+	kv = append(kv, ctxfield.Values(ctx)...)
 
 	l.logger.Errorw(msg, mask(kv...)...)
 }
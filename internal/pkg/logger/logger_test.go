@@ -0,0 +1,88 @@
+package logger
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// closable is a log output failing the test when written to once closed
+type closable struct {
+	t      *testing.T
+	closed atomic.Bool
+}
+
+func (w *closable) Write(p []byte) (int, error) {
+	// the output must not be closed in the middle of a write either
+	for range 2 {
+		if w.closed.Load() {
+			w.t.Error("write to a closed log output")
+			break
+		}
+		runtime.Gosched()
+	}
+	return len(p), nil
+}
+
+func newClosable(t *testing.T) *Logger {
+	w := &closable{t: t}
+	l := New(AddWriter(w, false), WithCaller(1))
+	l.stopFn = func() { w.closed.Store(true) }
+	return &l
+}
+
+func TestReplaceDefaultLoggerWhileLogging(t *testing.T) {
+	previous := Default()
+	t.Cleanup(func() { SetDefaultLogger(*previous) })
+	ReplaceDefaultLogger(newClosable(t))
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				Info("request", "path", "/api/v1/examples")
+				InfoCtx(context.Background(), "request done", "status", 200)
+			}
+		}()
+	}
+
+	// each reload closes the output of the previous logger, a fresh logger writes the first messages unsampled
+	for i := 0; i < 20; i++ {
+		time.Sleep(time.Millisecond)
+		ReplaceDefaultLogger(newClosable(t))
+	}
+	close(stop)
+	wg.Wait()
+}
+
+func TestStoppedDefaultLoggerStillLogs(t *testing.T) {
+	previous := Default()
+	t.Cleanup(func() { SetDefaultLogger(*previous) })
+
+	l := newClosable(t)
+	ReplaceDefaultLogger(l)
+	l.Stop()
+
+	// stopped without being replaced, e.g. on exit, the logs don't block
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		l, held := acquire()
+		defer release(l, held)
+		if held {
+			t.Error("expected the stopped logger not to be held")
+		}
+	}()
+	<-done
+}
@@ -0,0 +1,51 @@
+package logger
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// sink is a log file remembering its last write error, zap only reports them on stderr
+type sink struct {
+	*lumberjack.Logger
+
+	mu  sync.Mutex
+	err error
+}
+
+func (s *sink) Write(p []byte) (int, error) {
+	n, err := s.Logger.Write(p)
+
+	s.mu.Lock()
+	s.err = err
+	s.mu.Unlock()
+	return n, err
+}
+
+func (s *sink) lastErr() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err == nil {
+		return nil
+	}
+	return fmt.Errorf("%s: %w", s.Filename, s.err)
+}
+
+// SinkErr returns the errors of the log files whose last write failed, nil when every log file works
+func (l Logger) SinkErr() error {
+	var errs []error
+	for _, s := range l.sinks {
+		if err := s.lastErr(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// SinkErr returns the errors of the log files of the default logger, see Logger.SinkErr
+func SinkErr() error {
+	return Default().SinkErr()
+}
@@ -0,0 +1,53 @@
+package module
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"starter-go/internal/pkg/app"
+	"starter-go/internal/pkg/clock"
+	"starter-go/internal/pkg/flags"
+	"starter-go/internal/pkg/scheduler"
+)
+
+// Module is a domain of the service (e.g. example), it builds its repositories, services and handlers from the shared resources.
+// A module can also implement AppProvider and HealthChecker.
+type Module interface {
+	// Name identifies the module, in Dependencies and logs
+	Name() string
+	// Dependencies lists the names of the modules that must be initialized before this one
+	Dependencies() []string
+	// Init builds the module, it's called once, after the init of its dependencies
+	Init(res Resources) error
+	// RegisterRoutes adds the routes of the module, it's called after every module is initialized
+	RegisterRoutes(r *gin.Engine)
+}
+
+// AppProvider is implemented by modules running background apps (e.g. a consumer), they're started and stopped with the server
+type AppProvider interface {
+	Apps() []app.App
+}
+
+// HealthCheck reports whether a dependency of a module works, it returns nil when healthy
+type HealthCheck func(ctx context.Context) error
+
+// HealthChecker is implemented by modules with dependencies worth checking (e.g. a database)
+type HealthChecker interface {
+	HealthChecks() map[string]HealthCheck
+}
+
+// Resources are shared by every module.
+// Modules log with the logger package, its default logger follows config reloads.
+type Resources struct {
+	// DB is the database of the storage backend, nil for the memory backend
+	DB      *gorm.DB
+	Storage string
+	Clock   clock.Clock
+	Flags   *flags.Flags
+	// Scheduler runs the periodic jobs the modules add in Init
+	Scheduler *scheduler.Scheduler
+	// Modules gives access to the modules initialized before, e.g. to use the service of a dependency
+	Modules *Registry
+}
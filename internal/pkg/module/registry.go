@@ -0,0 +1,132 @@
+package module
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"starter-go/internal/pkg/app"
+	"starter-go/internal/pkg/logger"
+)
+
+// Registry holds the modules of the service, they're initialized in dependency order
+type Registry struct {
+	modules []Module
+	byName  map[string]Module
+}
+
+func NewRegistry() *Registry {
+	return &Registry{byName: map[string]Module{}}
+}
+
+// Register adds modules to r, names must be unique
+func (r *Registry) Register(modules ...Module) error {
+	for _, m := range modules {
+		if _, ok := r.byName[m.Name()]; ok {
+			return fmt.Errorf("module %s is already registered", m.Name())
+		}
+		r.byName[m.Name()] = m
+		r.modules = append(r.modules, m)
+	}
+	return nil
+}
+
+// Get returns the module registered with the name
+func (r *Registry) Get(name string) (Module, bool) {
+	m, ok := r.byName[name]
+	return m, ok
+}
+
+// Sorted returns the modules with every module after its dependencies, otherwise in registration order.
+// It fails on unknown dependencies and cycles.
+func (r *Registry) Sorted() ([]Module, error) {
+	sorted := make([]Module, 0, len(r.modules))
+	state := map[string]int{} // 1: visiting, 2: done
+
+	var visit func(m Module, path []string) error
+	visit = func(m Module, path []string) error {
+		switch state[m.Name()] {
+		case 1:
+			return fmt.Errorf("module dependency cycle: %s", strings.Join(append(path, m.Name()), " -> "))
+		case 2:
+			return nil
+		}
+
+		state[m.Name()] = 1
+		for _, dep := range m.Dependencies() {
+			d, ok := r.byName[dep]
+			if !ok {
+				return fmt.Errorf("module %s depends on unknown module %s", m.Name(), dep)
+			}
+			if err := visit(d, append(path, m.Name())); err != nil {
+				return err
+			}
+		}
+		state[m.Name()] = 2
+		sorted = append(sorted, m)
+		return nil
+	}
+
+	for _, m := range r.modules {
+		if err := visit(m, nil); err != nil {
+			return nil, err
+		}
+	}
+	return sorted, nil
+}
+
+// Init initializes every module in dependency order
+func (r *Registry) Init(res Resources) error {
+	sorted, err := r.Sorted()
+	if err != nil {
+		return err
+	}
+
+	res.Modules = r
+	for _, m := range sorted {
+		if err := m.Init(res); err != nil {
+			return fmt.Errorf("module %s: %w", m.Name(), err)
+		}
+		logger.Info("[Module] initialized", "module", m.Name())
+	}
+	return nil
+}
+
+// RegisterRoutes adds the routes of every module
+func (r *Registry) RegisterRoutes(e *gin.Engine) {
+	for _, m := range r.modules {
+		m.RegisterRoutes(e)
+	}
+}
+
+// Apps returns the background apps of every module implementing AppProvider
+func (r *Registry) Apps() []app.App {
+	var apps []app.App
+	for _, m := range r.modules {
+		if p, ok := m.(AppProvider); ok {
+			apps = append(apps, p.Apps()...)
+		}
+	}
+	return apps
+}
+
+// HealthChecks returns the checks of every module implementing HealthChecker, keyed by "module/check"
+func (r *Registry) HealthChecks() map[string]HealthCheck {
+	checks := map[string]HealthCheck{}
+	for _, m := range r.modules {
+		if c, ok := m.(HealthChecker); ok {
+			for name, check := range c.HealthChecks() {
+				checks[m.Name()+"/"+name] = check
+			}
+		}
+	}
+	return checks
+}
+
+// Names returns the sorted names of the registered modules
+func (r *Registry) Names() []string {
+	return slices.Sorted(maps.Keys(r.byName))
+}
@@ -0,0 +1,70 @@
+package module
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+type fakeModule struct {
+	name string
+	deps []string
+	init *[]string
+}
+
+func (m fakeModule) Name() string               { return m.name }
+func (m fakeModule) Dependencies() []string     { return m.deps }
+func (m fakeModule) RegisterRoutes(*gin.Engine) {}
+func (m fakeModule) Init(res Resources) error {
+	*m.init = append(*m.init, m.name)
+	return nil
+}
+
+func TestRegistryInitOrder(t *testing.T) {
+	var order []string
+	r := NewRegistry()
+	err := r.Register(
+		fakeModule{name: "loan", deps: []string{"customer", "product"}, init: &order},
+		fakeModule{name: "product", init: &order},
+		fakeModule{name: "customer", deps: []string{"product"}, init: &order},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := r.Init(Resources{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := strings.Join(order, ","); got != "product,customer,loan" {
+		t.Errorf("expected dependencies first, got %s", got)
+	}
+}
+
+func TestRegistryErrors(t *testing.T) {
+	var order []string
+
+	r := NewRegistry()
+	_ = r.Register(fakeModule{name: "a", init: &order})
+	if err := r.Register(fakeModule{name: "a", init: &order}); err == nil {
+		t.Error("expected duplicate name to be rejected")
+	}
+
+	r = NewRegistry()
+	_ = r.Register(fakeModule{name: "a", deps: []string{"missing"}, init: &order})
+	if err := r.Init(Resources{}); err == nil || !strings.Contains(err.Error(), "unknown module missing") {
+		t.Errorf("expected unknown dependency error, got %v", err)
+	}
+
+	r = NewRegistry()
+	_ = r.Register(
+		fakeModule{name: "a", deps: []string{"b"}, init: &order},
+		fakeModule{name: "b", deps: []string{"a"}, init: &order},
+	)
+	if err := r.Init(Resources{}); err == nil || !strings.Contains(err.Error(), "a -> b -> a") {
+		t.Errorf("expected cycle error, got %v", err)
+	}
+	if len(order) != 0 {
+		t.Errorf("expected no module to be initialized, got %v", order)
+	}
+}
@@ -0,0 +1,110 @@
+package ratelimit
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"starter-go/internal/pkg/clock"
+)
+
+// evictBatch is the most refilled buckets evicted by a request, so a request never walks the whole store
+const evictBatch = 8
+
+// MemoryStore keeps the buckets in the process, each instance limits on its own.
+// A bucket is evicted once full again, it's then the same as a new one, so the store only holds the keys seen recently.
+// The buckets are kept from the least recently used, every operation is O(1).
+type MemoryStore struct {
+	clock   clock.Clock
+	maxKeys int
+
+	mu      sync.Mutex
+	buckets map[string]*list.Element
+	// lru holds the *bucketState, the least recently used at the back
+	lru *list.List
+}
+
+type bucketState struct {
+	key     string
+	tokens  float64
+	updated time.Time
+	// full is when the bucket is full again, it can be evicted from then on
+	full time.Time
+}
+
+// NewMemoryStore returns an empty store holding up to maxKeys buckets.
+// Only full buckets are evicted, when the store is full and the least recently used bucket isn't full yet,
+// a new key is limited until it is, instead of resetting the bucket of another client.
+func NewMemoryStore(clk clock.Clock, maxKeys int) *MemoryStore {
+	return &MemoryStore{
+		clock:   clk,
+		maxKeys: max(maxKeys, 1),
+		buckets: make(map[string]*list.Element),
+		lru:     list.New(),
+	}
+}
+
+func (s *MemoryStore) Take(ctx context.Context, key string, bucket Bucket) (Result, error) {
+	now := s.clock.Now()
+	every := max(bucket.Every, time.Nanosecond)
+	burst := float64(bucket.Burst)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.evict(now, evictBatch)
+
+	var state *bucketState
+	if elem, ok := s.buckets[key]; ok {
+		s.lru.MoveToFront(elem)
+		state = elem.Value.(*bucketState)
+	} else {
+		if len(s.buckets) >= s.maxKeys {
+			// every bucket is still refilling, evicting one would hand its client a full bucket
+			wait := s.lru.Back().Value.(*bucketState).full.Sub(now)
+			return Result{RetryAfter: wait, ResetAfter: wait}, nil
+		}
+		state = &bucketState{key: key, tokens: burst, updated: now}
+		s.buckets[key] = s.lru.PushFront(state)
+	}
+
+	// tokens added since the last request, up to the size of the bucket
+	state.tokens = min(burst, state.tokens+float64(now.Sub(state.updated))/float64(every))
+	state.updated = now
+
+	var result Result
+	if state.tokens >= 1 {
+		state.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = time.Duration((1 - state.tokens) * float64(every))
+	}
+	result.Remaining = uint(state.tokens)
+	result.ResetAfter = time.Duration((burst - state.tokens) * float64(every))
+	state.full = now.Add(result.ResetAfter)
+	return result, nil
+}
+
+// Len returns the number of buckets held
+func (s *MemoryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.buckets)
+}
+
+// evict removes up to n least recently used buckets that are full again, it stops at the first one still refilling
+func (s *MemoryStore) evict(now time.Time, n int) {
+	for ; n > 0; n-- {
+		back := s.lru.Back()
+		if back == nil {
+			return
+		}
+		state := back.Value.(*bucketState)
+		if now.Before(state.full) {
+			return
+		}
+		s.lru.Remove(back)
+		delete(s.buckets, state.key)
+	}
+}
@@ -0,0 +1,118 @@
+// Package ratelimit limits the rate of requests with token buckets: a bucket holds up to Burst tokens,
+// a request takes one, and tokens are added back at a steady rate.
+package ratelimit
+
+import (
+	"context"
+	"crypto/subtle"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Keys the requests of a policy are counted by
+const (
+	KeyByIP      = "ip"
+	KeyByAPIKey  = "api_key"
+	KeyBySubject = "subject"
+)
+
+// Policy limits the requests of a route group, read from the ratelimit config section
+type Policy struct {
+	// Path is the route group, a path prefix of the routes
+	Path string
+	// Methods are the methods limited, empty limits every method
+	Methods []string
+	// Limit is the number of requests per Period
+	Limit  uint
+	Period time.Duration
+	// Burst is the size of the bucket, the requests allowed at once, 0 is Limit
+	Burst uint
+	// KeyBy is what the requests are counted by, KeyByIP, KeyByAPIKey or KeyBySubject
+	KeyBy string
+}
+
+// Bucket returns the bucket of the policy
+func (p Policy) Bucket() Bucket {
+	burst := p.Burst
+	if burst == 0 {
+		burst = p.Limit
+	}
+	return Bucket{Burst: burst, Every: p.Period / time.Duration(p.Limit)}
+}
+
+// Policies finds the policy of a request
+type Policies []Policy
+
+// NewPolicies returns the policies, the longest path first so the most specific one matches
+func NewPolicies(policies []Policy) Policies {
+	p := slices.Clone(policies)
+	for i := range p {
+		p[i].Path = strings.TrimSuffix(p[i].Path, "/")
+	}
+	sort.SliceStable(p, func(i, j int) bool {
+		return len(p[i].Path) > len(p[j].Path)
+	})
+	return p
+}
+
+// Match returns the policy of the route path, e.g. /api/v1/examples/:example_id, false when it isn't limited
+func (p Policies) Match(method, path string) (Policy, bool) {
+	for _, policy := range p {
+		if path != policy.Path && !strings.HasPrefix(path, policy.Path+"/") {
+			continue
+		}
+		if len(policy.Methods) == 0 || slices.ContainsFunc(policy.Methods, func(m string) bool { return strings.EqualFold(m, method) }) {
+			return policy, true
+		}
+	}
+	return Policy{}, false
+}
+
+// APIKey is a client whose requests can be counted by its API key
+type APIKey struct {
+	// Name identifies the client, its requests share the bucket of the name
+	Name string
+	Key  string
+}
+
+// APIKeys are the API keys the requests of the policies keyed by KeyByAPIKey are checked against
+type APIKeys []APIKey
+
+// Lookup returns the name of the client of key, false when key isn't one of k.
+// Every key is compared in constant time, so the time taken doesn't tell how close a guess is.
+func (k APIKeys) Lookup(key string) (string, bool) {
+	var name string
+	found := false
+	for _, apiKey := range k {
+		if subtle.ConstantTimeCompare([]byte(apiKey.Key), []byte(key)) == 1 && !found {
+			name, found = apiKey.Name, true
+		}
+	}
+	return name, found
+}
+
+// Bucket is the size and refill rate of a token bucket
+type Bucket struct {
+	Burst uint
+	// Every is how often a token is added
+	Every time.Duration
+}
+
+// Result is the state of a bucket after a request took a token
+type Result struct {
+	Allowed bool
+	// Remaining is the number of tokens left
+	Remaining uint
+	// RetryAfter is when the next token is added, for a request that isn't allowed
+	RetryAfter time.Duration
+	// ResetAfter is when the bucket is full again
+	ResetAfter time.Duration
+}
+
+// Store holds the buckets, MemoryStore keeps them in the process, a shared store (e.g. Redis) limits across the instances
+type Store interface {
+	// Take takes a token from the bucket of key, created full when it doesn't exist
+	Take(ctx context.Context, key string, bucket Bucket) (Result, error)
+}
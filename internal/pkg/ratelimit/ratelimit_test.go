@@ -0,0 +1,126 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"starter-go/internal/pkg/clock"
+)
+
+func TestMemoryStoreTake(t *testing.T) {
+	clk := clock.NewFake(time.Date(2025, 1, 31, 10, 0, 0, 0, time.UTC))
+	store := NewMemoryStore(clk, 100)
+	// 2 requests at once, then one every 30s
+	bucket := Policy{Limit: 2, Period: time.Minute}.Bucket()
+
+	steps := []struct {
+		advance    time.Duration
+		allowed    bool
+		remaining  uint
+		retryAfter time.Duration
+		resetAfter time.Duration
+	}{
+		{0, true, 1, 0, 30 * time.Second},
+		{0, true, 0, 0, time.Minute},
+		{0, false, 0, 30 * time.Second, time.Minute},
+		{10 * time.Second, false, 0, 20 * time.Second, 50 * time.Second},
+		{20 * time.Second, true, 0, 0, time.Minute},
+		// refilled, not above the burst
+		{5 * time.Minute, true, 1, 0, 30 * time.Second},
+	}
+	for i, step := range steps {
+		clk.Advance(step.advance)
+		got, err := store.Take(context.Background(), "client", bucket)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := Result{Allowed: step.allowed, Remaining: step.remaining, RetryAfter: step.retryAfter, ResetAfter: step.resetAfter}
+		if got != want {
+			t.Errorf("step %d: got %+v, want %+v", i, got, want)
+		}
+	}
+
+	if got, _ := store.Take(context.Background(), "other", bucket); !got.Allowed {
+		t.Error("the bucket of another key must be full")
+	}
+}
+
+func TestMemoryStoreEviction(t *testing.T) {
+	clk := clock.NewFake(time.Date(2025, 1, 31, 10, 0, 0, 0, time.UTC))
+	store := NewMemoryStore(clk, 3)
+	bucket := Bucket{Burst: 1, Every: time.Second}
+	take := func(key string) Result {
+		t.Helper()
+		got, err := store.Take(context.Background(), key, bucket)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return got
+	}
+
+	for i := 0; i < 3; i++ {
+		take(fmt.Sprintf("client%d", i))
+	}
+	// every bucket is still refilling, none is evicted, the new key waits for the oldest to be full
+	clk.Advance(400 * time.Millisecond)
+	if got := take("client3"); got.Allowed || got.RetryAfter != 600*time.Millisecond {
+		t.Errorf("expected the new key to be limited for 600ms, got %+v", got)
+	}
+	if got := store.Len(); got != 3 {
+		t.Errorf("len = %d, want 3", got)
+	}
+	// the clients kept their bucket
+	if got := take("client0"); got.Allowed {
+		t.Errorf("expected client0 to still be limited, got %+v", got)
+	}
+
+	// once full again, the buckets are evicted from the least recently used
+	clk.Advance(time.Second)
+	if got := take("client3"); !got.Allowed {
+		t.Errorf("expected the new key to be allowed once buckets are full again, got %+v", got)
+	}
+	if got := store.Len(); got != 1 {
+		t.Errorf("len = %d, want 1", got)
+	}
+}
+
+func TestAPIKeysLookup(t *testing.T) {
+	keys := APIKeys{{Name: "partner-a", Key: "key-a"}, {Name: "partner-b", Key: "key-b"}}
+
+	if name, ok := keys.Lookup("key-b"); !ok || name != "partner-b" {
+		t.Errorf("Lookup(key-b) = %q, %v, want partner-b, true", name, ok)
+	}
+	for _, key := range []string{"", "key", "key-c", "key-aa"} {
+		if name, ok := keys.Lookup(key); ok {
+			t.Errorf("Lookup(%q) = %q, expected an unknown key", key, name)
+		}
+	}
+}
+
+func TestPoliciesMatch(t *testing.T) {
+	policies := NewPolicies([]Policy{
+		{Path: "/api/v1", Limit: 100},
+		{Path: "/api/v1/examples/", Methods: []string{"POST"}, Limit: 10},
+	})
+
+	tests := []struct {
+		method, path string
+		want         uint
+		ok           bool
+	}{
+		{"POST", "/api/v1/examples", 10, true},
+		{"post", "/api/v1/examples/:example_id", 10, true},
+		{"GET", "/api/v1/examples", 100, true},
+		{"GET", "/api/v1", 100, true},
+		{"GET", "/api/v10/examples", 0, false},
+		{"GET", "/healthz/ready", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := policies.Match(tt.method, tt.path)
+		if ok != tt.ok || got.Limit != tt.want {
+			t.Errorf("Match(%s, %s) = %d, %v, want %d, %v", tt.method, tt.path, got.Limit, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,302 @@
+// Package scheduler runs periodic jobs (cleanups, reports, ...) inside the service, it's an app.App.
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"starter-go/internal/pkg/app"
+	"starter-go/internal/pkg/clock"
+	"starter-go/internal/pkg/health"
+	"starter-go/internal/pkg/logger"
+	"starter-go/internal/pkg/logger/contextid"
+	"starter-go/internal/pkg/logger/ctxfield"
+)
+
+// compile-time check to ensure Scheduler implements the app interfaces
+var (
+	_ app.App   = (*Scheduler)(nil)
+	_ app.Named = (*Scheduler)(nil)
+)
+
+// Func is the work of a job, ctx is canceled when the run times out or the scheduler stops
+type Func func(ctx context.Context) error
+
+// Option sets how a job runs
+type Option func(*job)
+
+// WithTimeout cancels the context of a run after timeout
+func WithTimeout(timeout time.Duration) Option {
+	return func(j *job) {
+		j.timeout = timeout
+	}
+}
+
+// Status is the outcome of the last run of a job
+type Status struct {
+	Name     string
+	Schedule string
+	Running  bool
+	LastRun  time.Time
+	LastErr  error
+	Duration time.Duration
+	NextRun  time.Time
+	// Skipped counts the runs skipped because the previous run was still running
+	Skipped int
+}
+
+type job struct {
+	name     string
+	schedule Schedule
+	run      Func
+	timeout  time.Duration
+
+	mu     sync.Mutex
+	status Status
+}
+
+// Scheduler runs jobs on their schedule, a job never overlaps with itself: a run due while the previous one is running is skipped
+type Scheduler struct {
+	clock clock.Clock
+
+	mu      sync.Mutex
+	jobs    []*job
+	started bool
+
+	// cancel cancels the context of the running jobs
+	cancel context.CancelFunc
+	// stopping is closed by Stop, no run starts afterwards
+	stopping chan struct{}
+	wg       sync.WaitGroup
+}
+
+// New returns a scheduler without jobs, cron schedules are evaluated in the location of clk (server.time_zone)
+func New(clk clock.Clock) *Scheduler {
+	return &Scheduler{clock: clk, stopping: make(chan struct{})}
+}
+
+// Add adds a job running fn on schedule, see Parse for the schedule syntax. Jobs are added before the scheduler starts.
+func (s *Scheduler) Add(name, schedule string, fn Func, opts ...Option) error {
+	sched, err := Parse(schedule)
+	if err != nil {
+		return fmt.Errorf("job %s: %w", name, err)
+	}
+	return s.AddSchedule(name, sched, fn, opts...)
+}
+
+// AddSchedule adds a job running fn on sched
+func (s *Scheduler) AddSchedule(name string, sched Schedule, fn Func, opts ...Option) error {
+	j := &job{name: name, schedule: sched, run: fn}
+	j.status = Status{Name: name, Schedule: fmt.Sprint(sched)}
+	for _, opt := range opts {
+		opt(j)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.started {
+		return fmt.Errorf("job %s: the scheduler is already started", name)
+	}
+	for _, other := range s.jobs {
+		if other.name == name {
+			return fmt.Errorf("job %s is already added", name)
+		}
+	}
+	s.jobs = append(s.jobs, j)
+	return nil
+}
+
+// Name is the name of the scheduler in the app logs
+func (s *Scheduler) Name() string {
+	return "scheduler"
+}
+
+// Start schedules the jobs in the background
+func (s *Scheduler) Start(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.started {
+		return errors.New("the scheduler is already started")
+	}
+	s.started = true
+
+	// runs outlive the start context, they're canceled by Stop
+	runCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	s.cancel = cancel
+	for _, j := range s.jobs {
+		s.wg.Add(1)
+		go s.loop(runCtx, j)
+	}
+	return nil
+}
+
+// Stop stops scheduling runs and waits for the running ones, their context is canceled when ctx is done
+func (s *Scheduler) Stop(ctx context.Context) error {
+	s.mu.Lock()
+	if !s.started {
+		s.mu.Unlock()
+		return nil
+	}
+	select {
+	case <-s.stopping:
+	default:
+		close(s.stopping)
+	}
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		s.cancel()
+		return nil
+	case <-ctx.Done():
+		// the jobs are told to give up, they're left behind if they don't
+		s.cancel()
+		return fmt.Errorf("running jobs didn't finish: %w", ctx.Err())
+	}
+}
+
+// loop runs j on its schedule until the scheduler stops, a run due while j is running is skipped
+func (s *Scheduler) loop(ctx context.Context, j *job) {
+	defer s.wg.Done()
+
+	next := j.schedule.Next(s.clock.Now())
+	for {
+		if next.IsZero() {
+			logger.Warn("[Scheduler] job has no next run, unscheduled", "job", j.name)
+			return
+		}
+		j.setNextRun(next)
+
+		timer := s.clock.NewTimer(next.Sub(s.clock.Now()))
+		select {
+		case <-timer.C():
+		case <-s.stopping:
+			timer.Stop()
+			return
+		}
+		// the timer and Stop can be ready together
+		select {
+		case <-s.stopping:
+			return
+		default:
+		}
+
+		s.runOnce(ctx, j)
+
+		// the runs due while j was running are skipped, it runs next at the first time after now
+		now := s.clock.Now()
+		next = j.schedule.Next(next)
+		for !next.IsZero() && !next.After(now) {
+			j.skip()
+			logger.Warn("[Scheduler] previous run still running, run skipped", "job", j.name, "due", next.String())
+			next = j.schedule.Next(next)
+		}
+	}
+}
+
+// runOnce runs j with a fresh context id for log correlation, a panic fails the run instead of crashing the service
+func (s *Scheduler) runOnce(ctx context.Context, j *job) {
+	ctx = ctxfield.New(contextid.New(ctx))
+	ctxfield.Set(ctx, "job", j.name)
+	if j.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, j.timeout)
+		defer cancel()
+	}
+
+	start := s.clock.Now()
+	j.begin(start)
+	logger.InfoCtx(ctx, "[Scheduler] job started")
+
+	err := func() (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("panic: %v", r)
+				logger.ErrorCtx(ctx, "[Scheduler] job panicked", "panic", fmt.Sprint(r), "stacktrace", string(debug.Stack()))
+			}
+		}()
+		return j.run(ctx)
+	}()
+
+	duration := s.clock.Now().Sub(start)
+	j.end(err, duration)
+	if err != nil {
+		logger.ErrorCtx(ctx, "[Scheduler] job failed", "duration", duration.String(), "error", err.Error())
+		return
+	}
+	logger.InfoCtx(ctx, "[Scheduler] job finished", "duration", duration.String())
+}
+
+func (j *job) setNextRun(next time.Time) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.status.NextRun = next
+}
+
+func (j *job) begin(start time.Time) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.status.Running = true
+	j.status.LastRun = start
+}
+
+func (j *job) end(err error, duration time.Duration) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.status.Running = false
+	j.status.LastErr = err
+	j.status.Duration = duration
+}
+
+func (j *job) skip() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.status.Skipped++
+}
+
+func (j *job) currentStatus() Status {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.status
+}
+
+// Status returns the status of every job, in the order they were added
+func (s *Scheduler) Status() []Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	statuses := make([]Status, len(s.jobs))
+	for i, j := range s.jobs {
+		statuses[i] = j.currentStatus()
+	}
+	return statuses
+}
+
+// HealthChecks returns a check per job, keyed by job name, down while the last run of the job failed.
+// A failed run doesn't stop the service from serving, register them with health.NonCritical.
+func (s *Scheduler) HealthChecks() map[string]health.Check {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	checks := make(map[string]health.Check, len(s.jobs))
+	for _, j := range s.jobs {
+		checks[j.name] = func(ctx context.Context) error {
+			status := j.currentStatus()
+			if status.LastErr != nil {
+				return fmt.Errorf("last run at %s failed: %w", status.LastRun.Format(time.RFC3339), status.LastErr)
+			}
+			return nil
+		}
+	}
+	return checks
+}
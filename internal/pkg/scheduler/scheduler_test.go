@@ -0,0 +1,244 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"starter-go/internal/pkg/clock"
+	"starter-go/internal/pkg/logger/contextid"
+)
+
+func TestParseNext(t *testing.T) {
+	jakarta := time.FixedZone("WIB", 7*60*60)
+	from := time.Date(2025, 1, 31, 10, 17, 30, 0, jakarta) // a friday
+
+	tests := []struct {
+		spec string
+		want time.Time
+	}{
+		{"*/15 * * * *", time.Date(2025, 1, 31, 10, 30, 0, 0, jakarta)},
+		{"0 * * * *", time.Date(2025, 1, 31, 11, 0, 0, 0, jakarta)},
+		{"@daily", time.Date(2025, 2, 1, 0, 0, 0, 0, jakarta)},
+		{"30 2 * * mon-fri", time.Date(2025, 2, 3, 2, 30, 0, 0, jakarta)},
+		{"0 9 1,15 * *", time.Date(2025, 2, 1, 9, 0, 0, 0, jakarta)},
+		{"0 0 29 feb *", time.Date(2028, 2, 29, 0, 0, 0, 0, jakarta)},
+		{"0 12 * * 7", time.Date(2025, 2, 2, 12, 0, 0, 0, jakarta)},
+		// day-of-month or day-of-week when both are set
+		{"0 0 13 * fri", time.Date(2025, 2, 7, 0, 0, 0, 0, jakarta)},
+		{"@every 90s", from.Add(90 * time.Second)},
+	}
+	for _, tt := range tests {
+		s, err := Parse(tt.spec)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.spec, err)
+			continue
+		}
+		if got := s.Next(from); !got.Equal(tt.want) {
+			t.Errorf("%s: expected %s, got %s", tt.spec, tt.want, got)
+		}
+	}
+
+	if next := mustParse(t, "0 0 30 2 *").Next(from); !next.IsZero() {
+		t.Errorf("expected february 30th never to run, got %s", next)
+	}
+
+	for _, bad := range []string{"* * * *", "60 * * * *", "* * * 13 *", "*/0 * * * *", "5-1 * * * *", "@every -1m", "@every soon", "@often"} {
+		if _, err := Parse(bad); err == nil {
+			t.Errorf("expected %q to fail", bad)
+		}
+	}
+}
+
+func mustParse(t *testing.T, spec string) Schedule {
+	t.Helper()
+	s, err := Parse(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func newScheduler() *Scheduler {
+	return New(clock.New(time.UTC))
+}
+
+func TestSchedulerRuns(t *testing.T) {
+	s := newScheduler()
+
+	var mu sync.Mutex
+	var ids []string
+	runs := make(chan struct{}, 10)
+	err := s.AddSchedule("tick", Every(10*time.Millisecond), func(ctx context.Context) error {
+		mu.Lock()
+		ids = append(ids, contextid.Value(ctx))
+		mu.Unlock()
+		runs <- struct{}{}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	<-runs
+	<-runs
+	if err := s.Stop(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if ids[0] == "" || ids[0] == ids[1] {
+		t.Errorf("expected a fresh context id per run, got %v", ids)
+	}
+	if status := s.Status()[0]; status.LastRun.IsZero() || status.LastErr != nil {
+		t.Errorf("expected a successful run, got %+v", status)
+	}
+}
+
+func TestSchedulerFollowsClock(t *testing.T) {
+	clk := clock.NewFake(time.Date(2025, 1, 31, 10, 17, 30, 0, time.UTC))
+	s := New(clk)
+
+	runs := make(chan time.Time, 10)
+	_ = s.Add("hourly", "@hourly", func(ctx context.Context) error {
+		runs <- clk.Now()
+		return nil
+	})
+	_ = s.Start(context.Background())
+	defer s.Stop(context.Background())
+
+	waitTimer(t, clk)
+	clk.Advance(42 * time.Minute)
+	select {
+	case <-runs:
+		t.Fatal("expected no run before the job is due")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	clk.Advance(30 * time.Second)
+	select {
+	case ran := <-runs:
+		if want := time.Date(2025, 1, 31, 11, 0, 0, 0, time.UTC); !ran.Equal(want) {
+			t.Errorf("expected the run at %s, got %s", want, ran)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the job to run once the clock reached 11:00")
+	}
+
+	waitTimer(t, clk)
+	if next := s.Status()[0].NextRun; !next.Equal(time.Date(2025, 1, 31, 12, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected the next run at 12:00, got %s", next)
+	}
+}
+
+// waitTimer waits for the scheduler to wait on a timer of clk
+func waitTimer(t *testing.T, clk *clock.Fake) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for clk.Timers() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the scheduler to set a timer")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestSchedulerFailures(t *testing.T) {
+	s := newScheduler()
+
+	panicked := make(chan struct{}, 10)
+	_ = s.AddSchedule("panics", Every(10*time.Millisecond), func(ctx context.Context) error {
+		defer func() { panicked <- struct{}{} }()
+		panic("boom")
+	})
+	_ = s.AddSchedule("fails", Every(10*time.Millisecond), func(ctx context.Context) error {
+		return errors.New("report failed")
+	})
+	_ = s.Start(context.Background())
+	defer s.Stop(context.Background())
+
+	// the scheduler keeps running the job after a panic
+	<-panicked
+	<-panicked
+
+	checks := s.HealthChecks()
+	deadline := time.Now().Add(time.Second)
+	for checks["fails"](context.Background()) == nil && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if err := checks["fails"](context.Background()); err == nil || !strings.Contains(err.Error(), "report failed") {
+		t.Errorf("expected the check to report the failed run, got %v", err)
+	}
+	if err := checks["panics"](context.Background()); err == nil || !strings.Contains(err.Error(), "panic: boom") {
+		t.Errorf("expected the check to report the panic, got %v", err)
+	}
+}
+
+func TestSchedulerNoOverlap(t *testing.T) {
+	s := newScheduler()
+
+	var running, maxRunning atomic.Int32
+	done := make(chan struct{}, 10)
+	_ = s.AddSchedule("slow", Every(5*time.Millisecond), func(ctx context.Context) error {
+		n := running.Add(1)
+		defer running.Add(-1)
+		if n > maxRunning.Load() {
+			maxRunning.Store(n)
+		}
+		time.Sleep(30 * time.Millisecond)
+		done <- struct{}{}
+		return nil
+	})
+	_ = s.Start(context.Background())
+	<-done
+	<-done
+	_ = s.Stop(context.Background())
+
+	if maxRunning.Load() != 1 {
+		t.Errorf("expected runs not to overlap, got %d at once", maxRunning.Load())
+	}
+	if skipped := s.Status()[0].Skipped; skipped == 0 {
+		t.Error("expected the runs due while running to be skipped")
+	}
+}
+
+func TestSchedulerStop(t *testing.T) {
+	s := newScheduler()
+
+	started := make(chan struct{})
+	canceled := make(chan struct{})
+	_ = s.AddSchedule("long", Every(time.Millisecond), func(ctx context.Context) error {
+		close(started)
+		<-ctx.Done()
+		close(canceled)
+		return ctx.Err()
+	})
+	_ = s.Start(context.Background())
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := s.Stop(ctx)
+	if err == nil || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the stop to time out, got %v", err)
+	}
+
+	// the running job is canceled once the stop deadline passed
+	select {
+	case <-canceled:
+	case <-time.After(time.Second):
+		t.Fatal("expected the running job to be canceled")
+	}
+
+	if err := s.AddSchedule("late", Every(time.Second), func(ctx context.Context) error { return nil }); err == nil {
+		t.Error("expected adding a job to a started scheduler to fail")
+	}
+}
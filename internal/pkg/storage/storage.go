@@ -0,0 +1,47 @@
+package storage
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"starter-go/internal/pkg/clock"
+	"starter-go/internal/pkg/config"
+	"starter-go/internal/pkg/driver/mysql"
+	"starter-go/internal/pkg/driver/postgres"
+)
+
+// Backends of storage.backend
+const (
+	Memory   = "memory"
+	MySQL    = "mysql"
+	Postgres = "postgres"
+)
+
+// NewDatabase connects to the database of the backend, retrying at startup like the drivers do.
+// It returns nil for the memory backend, which doesn't need a database.
+func NewDatabase(conf config.StorageConfig, dbConf config.DatabaseConfig, clk clock.Clock) *gorm.DB {
+	switch conf.GetBackend() {
+	case MySQL:
+		return mysql.NewDatabase(dbConf, clk)
+	case Postgres:
+		return postgres.NewDatabase(dbConf, clk)
+	default:
+		return nil
+	}
+}
+
+// Open connects to the database of the backend once, e.g. to check it's reachable.
+// It returns nil for the memory backend.
+func Open(conf config.StorageConfig, dbConf config.DatabaseConfig, clk clock.Clock) (*gorm.DB, error) {
+	switch conf.GetBackend() {
+	case Memory:
+		return nil, nil
+	case MySQL:
+		return mysql.Open(dbConf, clk)
+	case Postgres:
+		return postgres.Open(dbConf, clk)
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", conf.GetBackend())
+	}
+}
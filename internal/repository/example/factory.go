@@ -0,0 +1,47 @@
+package example
+
+import (
+	"context"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"starter-go/internal/domain/example"
+	"starter-go/internal/pkg/clock"
+	"starter-go/internal/pkg/logger"
+	"starter-go/internal/pkg/storage"
+)
+
+// NewRepository builds the repository of the storage backend, db is the database opened by storage.NewDatabase (nil for memory).
+// The examples table of the database backends is migrated, e.g. created_at is added to a table created before it.
+func NewRepository(backend string, db *gorm.DB, clk clock.Clock) (example.ExampleRepository, error) {
+	switch backend {
+	case storage.Memory:
+		return newPreloadedMemoryRepository(clk)
+	case storage.MySQL, storage.Postgres:
+		if db == nil {
+			return nil, fmt.Errorf("storage backend %s needs a database", backend)
+		}
+		if err := db.AutoMigrate(&ExampleModel{}); err != nil {
+			return nil, fmt.Errorf("failed to migrate the examples table: %w", err)
+		}
+		return NewExampleRepository(db), nil
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", backend)
+	}
+}
+
+// newPreloadedMemoryRepository returns a memory repository prepopulated with some examples, for testing the endpoints
+func newPreloadedMemoryRepository(clk clock.Clock) (example.ExampleRepository, error) {
+	repo := NewMemoryRepository(clk)
+	err := repo.Preload(
+		context.Background(),
+		&example.Example{Description: "Example 1"},
+		&example.Example{Description: "Example 2"},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to preload examples: %w", err)
+	}
+	logger.Info("Pre-populated repository with examples")
+	return repo, nil
+}
@@ -6,20 +6,24 @@ import (
 	"sync"
 
 	"starter-go/internal/domain/example"
+	"starter-go/internal/pkg/clock"
 )
 
 // MemoryRepository is an in-memory implementation of the example.ExampleRepository interface
 type MemoryRepository struct {
 	examples map[int]*example.Example
 	nextID   int
+	clock    clock.Clock
 	mu       sync.RWMutex // For thread safety
 }
 
-// NewMemoryRepository creates a new in-memory repository
-func NewMemoryRepository() *MemoryRepository {
+// NewMemoryRepository creates a new in-memory repository,
+// clk stamps the creation time of examples saved without one (like a database default)
+func NewMemoryRepository(clk clock.Clock) *MemoryRepository {
 	return &MemoryRepository{
 		examples: make(map[int]*example.Example),
 		nextID:   1,
+		clock:    clk,
 	}
 }
 
@@ -33,6 +37,7 @@ func (r *MemoryRepository) FindByID(ctx context.Context, id int) (*example.Examp
 		return &example.Example{
 			ID:          ex.ID,
 			Description: ex.Description,
+			CreatedAt:   ex.CreatedAt,
 		}, nil
 	}
 	return nil, fmt.Errorf("example with ID %d not found", id)
@@ -48,6 +53,7 @@ func (r *MemoryRepository) FindAll(ctx context.Context) ([]*example.Example, err
 		result = append(result, &example.Example{
 			ID:          ex.ID,
 			Description: ex.Description,
+			CreatedAt:   ex.CreatedAt,
 		})
 	}
 	return result, nil
@@ -63,11 +69,15 @@ func (r *MemoryRepository) Save(ctx context.Context, e *example.Example) error {
 		e.ID = r.nextID
 		r.nextID++
 	}
+	if e.CreatedAt.IsZero() {
+		e.CreatedAt = r.clock.Now()
+	}
 
 	// Store a copy to prevent external modification
 	exampleCopy := &example.Example{
 		ID:          e.ID,
 		Description: e.Description,
+		CreatedAt:   e.CreatedAt,
 	}
 	r.examples[e.ID] = exampleCopy
 
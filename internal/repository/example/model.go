@@ -1,10 +1,15 @@
 package example
 
-import "starter-go/internal/domain/example"
+import (
+	"time"
+
+	"starter-go/internal/domain/example"
+)
 
 type ExampleModel struct {
 	ID          uint `gorm:"primaryKey"`
 	Description string
+	CreatedAt   time.Time
 }
 
 func (ExampleModel) TableName() string {
@@ -12,9 +17,9 @@ func (ExampleModel) TableName() string {
 }
 
 func (e *ExampleModel) ToDomain() *example.Example {
-	return &example.Example{ID: int(e.ID), Description: e.Description}
+	return &example.Example{ID: int(e.ID), Description: e.Description, CreatedAt: e.CreatedAt}
 }
 
 func FromDomain(e *example.Example) *ExampleModel {
-	return &ExampleModel{ID: uint(e.ID), Description: e.Description}
+	return &ExampleModel{ID: uint(e.ID), Description: e.Description, CreatedAt: e.CreatedAt}
 }
@@ -43,5 +43,7 @@ func (r *ExampleRepository) Save(ctx context.Context, e *example.Example) error
 		return err
 	}
 	e.ID = int(model.ID)
+	// set by gorm, from the NowFunc of the database, when the example didn't have one
+	e.CreatedAt = model.CreatedAt
 	return nil
 }
@@ -3,6 +3,7 @@ package example
 import (
 	"context"
 	entity "starter-go/internal/domain/example"
+	"starter-go/internal/pkg/clock"
 	"starter-go/internal/pkg/errors"
 )
 
@@ -11,11 +12,12 @@ import (
 var _ entity.ExampleService = (*ExampleService)(nil)
 
 type ExampleService struct {
-	repo entity.ExampleRepository
+	repo  entity.ExampleRepository
+	clock clock.Clock
 }
 
-func NewService(repo entity.ExampleRepository) *ExampleService {
-	return &ExampleService{repo: repo}
+func NewService(repo entity.ExampleRepository, clk clock.Clock) *ExampleService {
+	return &ExampleService{repo: repo, clock: clk}
 }
 
 func (s *ExampleService) GetExample(ctx context.Context, id int) (*entity.Example, error) {
@@ -35,7 +37,7 @@ func (s *ExampleService) GetAllExamples(ctx context.Context) ([]*entity.Example,
 }
 
 func (s *ExampleService) CreateExample(ctx context.Context, desc string) (*entity.Example, error) {
-	e := &entity.Example{Description: desc}
+	e := &entity.Example{Description: desc, CreatedAt: s.clock.Now()}
 	err := s.repo.Save(ctx, e)
 	if err != nil {
 		return nil, errors.New("EXAMPLE_CREATE_FAILED", "Failed to create example", err)
@@ -0,0 +1,141 @@
+package admin_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	server "starter-go/api/rest"
+	"starter-go/api/rest/admin"
+	"starter-go/internal/pkg/driver/httpserver/middleware"
+	"starter-go/internal/pkg/health"
+)
+
+const token = "s3cret"
+
+// dbError is the error of the failing check, it names a host so it must stay off the public port
+const dbError = "dial tcp db.internal:3306: connect: connection refused"
+
+// setupRouters returns the engine of the admin server, listing its routes and the ones of the public engine also returned
+func setupRouters() (*gin.Engine, *gin.Engine) {
+	gin.SetMode(gin.TestMode)
+	checks := health.NewRegistry(time.Second, 0)
+	checks.Register("example/database", func(ctx context.Context) error {
+		return errors.New(dbError)
+	})
+
+	public := gin.New()
+	server.RegisterRoutes(public, checks)
+	public.GET("/api/v1/examples", func(c *gin.Context) {})
+
+	r := gin.New()
+	r.Use(middleware.ErrorHandler())
+	h := admin.NewHandler(map[string]*gin.Engine{"http": public, "admin": r}, checks)
+	admin.RegisterRoutes(r, h, func() string { return token })
+	return r, public
+}
+
+func get(r *gin.Engine, path, auth string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if auth != "" {
+		req.Header.Set("Authorization", auth)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestAdminToken(t *testing.T) {
+	r, _ := setupRouters()
+
+	tests := []struct {
+		name           string
+		path           string
+		auth           string
+		expectedStatus int
+	}{
+		{"Missing token", "/admin/runtime", "", http.StatusUnauthorized},
+		{"Wrong token", "/admin/runtime", "Bearer nope", http.StatusUnauthorized},
+		{"Not a bearer token", "/admin/runtime", token, http.StatusUnauthorized},
+		{"Missing token on pprof", "/debug/pprof/", "", http.StatusUnauthorized},
+		{"Valid token", "/admin/runtime", "Bearer " + token, http.StatusOK},
+		{"Valid token on pprof", "/debug/pprof/", "Bearer " + token, http.StatusOK},
+		{"Valid token on expvar", "/debug/vars", "Bearer " + token, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := get(r, tt.path, tt.auth)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedStatus == http.StatusUnauthorized {
+				assert.Equal(t, "Bearer", w.Header().Get("WWW-Authenticate"))
+				assert.Contains(t, w.Body.String(), "UNAUTHORIZED")
+			}
+		})
+	}
+}
+
+func TestAdminRoutes(t *testing.T) {
+	r, _ := setupRouters()
+
+	w := get(r, "/admin/routes", "Bearer "+token)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var routes []map[string]string
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &routes))
+
+	servers := map[string]bool{}
+	for _, route := range routes {
+		servers[route["server"]+" "+route["method"]+" "+route["path"]] = true
+	}
+	assert.True(t, servers["http GET /api/v1/examples"], "expected the public routes, got %v", routes)
+	assert.True(t, servers["admin GET /debug/pprof/heap"], "expected the admin routes, got %v", routes)
+}
+
+func TestAdminRuntimeAndConfig(t *testing.T) {
+	r, _ := setupRouters()
+
+	w := get(r, "/admin/runtime", "Bearer "+token)
+	var stats struct {
+		Goroutines int `json:"goroutines"`
+		Memory     struct {
+			Sys uint64 `json:"sys"`
+		} `json:"memory"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &stats))
+	assert.Positive(t, stats.Goroutines)
+	assert.Positive(t, stats.Memory.Sys)
+
+	w = get(r, "/admin/config/hash", "Bearer "+token)
+	var hash map[string]string
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &hash))
+	assert.Len(t, hash["hash"], 64)
+
+	w = get(r, "/admin/config", "Bearer "+token)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, hash["hash"], w.Header().Get("X-Config-Hash"))
+}
+
+func TestAdminHealth(t *testing.T) {
+	r, public := setupRouters()
+
+	w := get(r, "/admin/health", "Bearer "+token)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	var report health.Report
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+	assert.Equal(t, dbError, report.Checks["example/database"].Error)
+
+	// the public probe only has the status of the checks
+	w = get(public, "/healthz/ready", "")
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.JSONEq(t, `{"status":"down","checks":{"example/database":"down"}}`, w.Body.String())
+	assert.NotContains(t, w.Body.String(), "db.internal")
+}
@@ -8,14 +8,18 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 
 	"starter-go/api/rest/example"
 	entity "starter-go/internal/domain/example"
+	"starter-go/internal/pkg/clock"
 	"starter-go/internal/pkg/driver/httpserver/middleware"
 	pkgErrors "starter-go/internal/pkg/errors"
+	"starter-go/internal/pkg/flags"
+	"starter-go/internal/pkg/ratelimit"
 )
 
 type mockExampleService struct {
@@ -36,6 +40,8 @@ func (m *mockExampleService) GetAllExamples(ctx context.Context) ([]*entity.Exam
 	return m.getAllExample(ctx)
 }
 
+var jakarta = time.FixedZone("WIB", 7*60*60)
+
 func setupRouter() *gin.Engine {
 	gin.SetMode(gin.TestMode)
 	r := gin.New()
@@ -58,12 +64,14 @@ func TestGetExample(t *testing.T) {
 			mockResponse: &entity.Example{
 				ID:          123,
 				Description: "Test Example",
+				CreatedAt:   time.Date(2025, 1, 2, 3, 4, 5, 0, jakarta),
 			},
 			mockError:      nil,
 			expectedStatus: http.StatusOK,
 			expectedBody: map[string]interface{}{
 				"id":          float64(123), // JSON numbers are parsed as float64
 				"description": "Test Example",
+				"created_at":  "2025-01-02T03:04:05+07:00",
 			},
 		},
 		{
@@ -127,12 +135,14 @@ func TestCreateExampleEndpoint(t *testing.T) {
 			mockResponse: &entity.Example{
 				ID:          1,
 				Description: "New Example",
+				CreatedAt:   time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
 			},
 			mockError:      nil,
 			expectedStatus: http.StatusCreated,
 			expectedBody: map[string]interface{}{
 				"id":          float64(1),
 				"description": "New Example",
+				"created_at":  "2025-01-02T03:04:05Z",
 			},
 		},
 		{
@@ -185,3 +195,171 @@ func TestCreateExampleEndpoint(t *testing.T) {
 		})
 	}
 }
+
+func TestGetAllExamplesTimeout(t *testing.T) {
+	tests := []struct {
+		name           string
+		header         string
+		expectedStatus int
+		// maxTimeout is the longest deadline the service may get
+		maxTimeout time.Duration
+	}{
+		{
+			name:           "Route Timeout",
+			expectedStatus: http.StatusGatewayTimeout,
+			maxTimeout:     50 * time.Millisecond,
+		},
+		{
+			name:           "Shorter Client Timeout",
+			header:         "20",
+			expectedStatus: http.StatusGatewayTimeout,
+			maxTimeout:     20 * time.Millisecond,
+		},
+		{
+			name:           "Longer Client Timeout Capped",
+			header:         "5s",
+			expectedStatus: http.StatusGatewayTimeout,
+			maxTimeout:     50 * time.Millisecond,
+		},
+		{
+			name:           "Invalid Client Timeout",
+			header:         "soon",
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := setupRouter()
+			timeouts := middleware.NewTimeout(time.Second, map[string]time.Duration{"/api/v1/examples": 50 * time.Millisecond})
+			r.Use(timeouts.Handler())
+
+			var timeout time.Duration
+			mockSvc := &mockExampleService{
+				getAllExample: func(ctx context.Context) ([]*entity.Example, error) {
+					deadline, _ := ctx.Deadline()
+					timeout = time.Until(deadline)
+					// a slow query giving up once the context is done
+					<-ctx.Done()
+					return nil, pkgErrors.New("EXAMPLE_FETCH_FAILED", "Failed to fetch examples", ctx.Err())
+				},
+			}
+
+			handler := example.NewHandler(mockSvc)
+			example.RegisterRoutes(r, handler)
+
+			req, _ := http.NewRequest("GET", "/api/v1/examples/", nil)
+			if tt.header != "" {
+				req.Header.Set("X-Request-Timeout", tt.header)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedStatus != http.StatusGatewayTimeout {
+				return
+			}
+			assert.LessOrEqual(t, timeout, tt.maxTimeout)
+
+			var response map[string]interface{}
+			err := json.Unmarshal(w.Body.Bytes(), &response)
+			assert.NoError(t, err)
+			assert.Equal(t, pkgErrors.CodeTimeout, response["code"])
+			assert.Equal(t, "Request timed out", response["message"])
+		})
+	}
+}
+
+func TestCreateExampleRateLimited(t *testing.T) {
+	r := setupRouter()
+	limiter := middleware.NewRateLimit(
+		ratelimit.NewMemoryStore(clock.NewFake(time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)), 100),
+		"X-API-Key",
+		[]ratelimit.Policy{{Path: "/api/v1/examples", Methods: []string{"POST"}, Limit: 2, Period: time.Minute, KeyBy: ratelimit.KeyByAPIKey}},
+		nil,
+		func(c *gin.Context, key string) (string, bool) {
+			return ratelimit.APIKeys{{Name: "client-a", Key: "key-a"}, {Name: "client-b", Key: "key-b"}}.Lookup(key)
+		},
+	)
+	r.Use(limiter.Handler())
+
+	mockSvc := &mockExampleService{
+		createExample: func(ctx context.Context, desc string) (*entity.Example, error) {
+			return &entity.Example{ID: 1, Description: desc}, nil
+		},
+		getAllExample: func(ctx context.Context) ([]*entity.Example, error) {
+			return nil, nil
+		},
+	}
+	example.RegisterRoutes(r, example.NewHandler(mockSvc))
+
+	create := func(apiKey string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", "/api/v1/examples/", bytes.NewBufferString(`{"description":"New Example"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-API-Key", apiKey)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	for i, remaining := range []string{"1", "0"} {
+		w := create("key-a")
+		assert.Equal(t, http.StatusCreated, w.Code, "request %d", i)
+		assert.Equal(t, "2", w.Header().Get("RateLimit-Limit"))
+		assert.Equal(t, remaining, w.Header().Get("RateLimit-Remaining"))
+		assert.Equal(t, "2;w=60;burst=2", w.Header().Get("RateLimit-Policy"))
+	}
+
+	w := create("key-a")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "30", w.Header().Get("Retry-After"))
+	assert.Equal(t, "60", w.Header().Get("RateLimit-Reset"))
+	var response map[string]interface{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, pkgErrors.CodeRateLimited, response["code"])
+
+	// another API key has its own bucket, and GET isn't limited
+	assert.Equal(t, http.StatusCreated, create("key-b").Code)
+
+	// unknown keys share the bucket of the IP, making keys up doesn't give fresh buckets
+	assert.Equal(t, http.StatusCreated, create("made-up-1").Code)
+	assert.Equal(t, http.StatusCreated, create("made-up-2").Code)
+	assert.Equal(t, http.StatusTooManyRequests, create("made-up-3").Code)
+	req, _ := http.NewRequest("GET", "/api/v1/examples/", nil)
+	req.Header.Set("X-API-Key", "key-a")
+	get := httptest.NewRecorder()
+	r.ServeHTTP(get, req)
+	assert.Equal(t, http.StatusOK, get.Code)
+	assert.Empty(t, get.Header().Get("RateLimit-Limit"))
+}
+
+func TestCreateExampleFlagsFromRequestContext(t *testing.T) {
+	r := setupRouter()
+	featureFlags := flags.New([]flags.Flag{
+		// httptest requests come from 192.0.2.1
+		{Name: "by_ip", Enabled: true, Rollout: 0, RolloutBy: flags.RolloutByClientIP, Allow: []string{"192.0.2.1"}},
+		{Name: "by_request_id", Enabled: true, Rollout: 0, RolloutBy: flags.RolloutByClientIP, Allow: []string{"chosen-by-client"}},
+	})
+	r.Use(middleware.Flags(featureFlags, nil))
+
+	enabled := map[string]bool{}
+	mockSvc := &mockExampleService{
+		createExample: func(ctx context.Context, desc string) (*entity.Example, error) {
+			for _, name := range []string{"by_ip", "by_request_id"} {
+				enabled[name] = flags.Enabled(ctx, name)
+			}
+			return &entity.Example{ID: 1, Description: desc}, nil
+		},
+	}
+	example.RegisterRoutes(r, example.NewHandler(mockSvc))
+
+	req := httptest.NewRequest("POST", "/api/v1/examples/", bytes.NewBufferString(`{"description":"New Example"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Request-Id", "chosen-by-client")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.True(t, enabled["by_ip"], "expected the service to evaluate the flags of the request")
+	assert.False(t, enabled["by_request_id"], "expected X-Request-Id not to pick the flag state")
+}
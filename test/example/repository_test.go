@@ -0,0 +1,37 @@
+package example_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"starter-go/internal/pkg/clock"
+	"starter-go/internal/pkg/storage"
+	exampleRepo "starter-go/internal/repository/example"
+)
+
+func TestNewRepository(t *testing.T) {
+	clk := clock.NewFake(time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC))
+
+	repo, err := exampleRepo.NewRepository(storage.Memory, nil, clk)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := repo.(*exampleRepo.MemoryRepository); !ok {
+		t.Errorf("expected memory repository, got %T", repo)
+	}
+	// the memory backend starts with the examples to try the endpoints with
+	if examples, err := repo.FindAll(context.Background()); err != nil || len(examples) != 2 {
+		t.Errorf("expected 2 preloaded examples, got %d (%v)", len(examples), err)
+	}
+
+	// database backends fail fast instead of panicking on first use
+	for _, backend := range []string{storage.MySQL, storage.Postgres} {
+		if _, err := exampleRepo.NewRepository(backend, nil, clk); err == nil {
+			t.Errorf("expected %s without a database to fail", backend)
+		}
+	}
+	if _, err := exampleRepo.NewRepository("sqlite", nil, clk); err == nil {
+		t.Error("expected unknown backend to fail")
+	}
+}
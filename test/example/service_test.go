@@ -4,8 +4,10 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"starter-go/internal/domain/example"
+	"starter-go/internal/pkg/clock"
 	exampleService "starter-go/internal/service/example"
 )
 
@@ -34,7 +36,7 @@ func TestGetExample_Success(t *testing.T) {
 		},
 	}
 
-	service := exampleService.NewService(mock)
+	service := exampleService.NewService(mock, clock.NewFake(time.Now()))
 	ctx := context.Background()
 	e, err := service.GetExample(ctx, 1)
 	if err != nil || e.Description != "desc" {
@@ -49,7 +51,7 @@ func TestGetExample_NotFound(t *testing.T) {
 		},
 	}
 
-	service := exampleService.NewService(mock)
+	service := exampleService.NewService(mock, clock.NewFake(time.Now()))
 	ctx := context.Background()
 	_, err := service.GetExample(ctx, 123)
 	if err == nil {
@@ -67,7 +69,8 @@ func TestCreateExample(t *testing.T) {
 		},
 	}
 
-	service := exampleService.NewService(mock)
+	now := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	service := exampleService.NewService(mock, clock.NewFake(now))
 	ctx := context.Background()
 	example, err := service.CreateExample(ctx, "creating")
 	if err != nil || !saved {
@@ -76,4 +79,7 @@ func TestCreateExample(t *testing.T) {
 	if example.ID != 1 {
 		t.Fatalf("expected ID to be set, got %d", example.ID)
 	}
+	if !example.CreatedAt.Equal(now) {
+		t.Fatalf("expected CreatedAt to come from the clock, got %s", example.CreatedAt)
+	}
 }